@@ -1 +1,7 @@
-package bybitpoller
+package main
+
+import "DataPoller/internal/app/bybitpoller"
+
+func main() {
+	bybitpoller.RunBybitPoller()
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"DataPoller/internal/app/datapoller"
+	"os"
+)
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			datapoller.RunMigrate(os.Args[2:])
+			return
+		case "export":
+			datapoller.RunExport(os.Args[2:])
+			return
+		}
+	}
+	datapoller.RunDataPoller()
+}
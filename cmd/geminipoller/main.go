@@ -0,0 +1,7 @@
+package main
+
+import "DataPoller/internal/app/geminipoller"
+
+func main() {
+	geminipoller.RunGeminiPoller()
+}
@@ -1 +1,7 @@
-package krakenpoller
+package main
+
+import "DataPoller/internal/app/krakenpoller"
+
+func main() {
+	krakenpoller.RunKrakenPoller()
+}
@@ -2,9 +2,53 @@ package binancepoller
 
 import (
 	"DataPoller/internal/common/application/services/quotePollersFactories"
+	"DataPoller/internal/common/domain/consts"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func RunBinancePoller() {
-	binancePoller := quotePollersFactories.BuildBinanceQuotePoller()
-	(*binancePoller).Poll()
+	configPath := infrastructure.ConfigPathFlag()
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	configuration, err := infrastructure.LoadConfiguration(*configPath)
+	if err != nil {
+		log.Fatal("Error loading configuration:", err)
+	}
+	if err := logging.Setup(configuration.Logging); err != nil {
+		log.Fatal("Error setting up logging:", err)
+	}
+
+	factory, err := quotePollersFactories.NewDefaultQuotePollerFactory(ctx, configuration)
+	if err != nil {
+		log.Fatal("Error creating Binance poller factory:", err)
+	}
+
+	binancePoller, err := factory.Build(ctx, consts.Binance)
+	if err != nil {
+		log.Fatal("Error building Binance poller:", err)
+	}
+
+	go func() {
+		if err := factory.FollowSymbolPairs(ctx); err != nil {
+			slog.Error("Error watching data source changes", logging.Error(err))
+		}
+	}()
+
+	if err := binancePoller.Poll(ctx); err != nil {
+		slog.Error("Binance poller stopped with error", logging.Error(err))
+	}
+	if err := factory.Close(); err != nil {
+		slog.Error("Error closing Binance quotes writer", logging.Error(err))
+	}
 }
@@ -2,9 +2,53 @@ package bitfinexpoller
 
 import (
 	"DataPoller/internal/common/application/services/quotePollersFactories"
+	"DataPoller/internal/common/domain/consts"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func RunBitfinexPoller() {
-	bitfinexPoller := quotePollersFactories.BuildBitfinexQuotePoller()
-	(*bitfinexPoller).Poll()
+	configPath := infrastructure.ConfigPathFlag()
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	configuration, err := infrastructure.LoadConfiguration(*configPath)
+	if err != nil {
+		log.Fatal("Error loading configuration:", err)
+	}
+	if err := logging.Setup(configuration.Logging); err != nil {
+		log.Fatal("Error setting up logging:", err)
+	}
+
+	factory, err := quotePollersFactories.NewDefaultQuotePollerFactory(ctx, configuration)
+	if err != nil {
+		log.Fatal("Error creating Bitfinex poller factory:", err)
+	}
+
+	bitfinexPoller, err := factory.Build(ctx, consts.Bitfinex)
+	if err != nil {
+		log.Fatal("Error building Bitfinex poller:", err)
+	}
+
+	go func() {
+		if err := factory.FollowSymbolPairs(ctx); err != nil {
+			slog.Error("Error watching data source changes", logging.Error(err))
+		}
+	}()
+
+	if err := bitfinexPoller.Poll(ctx); err != nil {
+		slog.Error("Bitfinex poller stopped with error", logging.Error(err))
+	}
+	if err := factory.Close(); err != nil {
+		slog.Error("Error closing Bitfinex quotes writer", logging.Error(err))
+	}
 }
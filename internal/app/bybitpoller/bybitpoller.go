@@ -1 +1,54 @@
 package bybitpoller
+
+import (
+	"DataPoller/internal/common/application/services/quotePollersFactories"
+	"DataPoller/internal/common/domain/consts"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func RunBybitPoller() {
+	configPath := infrastructure.ConfigPathFlag()
+	flag.Parse()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	configuration, err := infrastructure.LoadConfiguration(*configPath)
+	if err != nil {
+		log.Fatal("Error loading configuration:", err)
+	}
+	if err := logging.Setup(configuration.Logging); err != nil {
+		log.Fatal("Error setting up logging:", err)
+	}
+
+	factory, err := quotePollersFactories.NewDefaultQuotePollerFactory(ctx, configuration)
+	if err != nil {
+		log.Fatal("Error creating Bybit poller factory:", err)
+	}
+
+	bybitPoller, err := factory.Build(ctx, consts.ByBit)
+	if err != nil {
+		log.Fatal("Error building Bybit poller:", err)
+	}
+
+	go func() {
+		if err := factory.FollowSymbolPairs(ctx); err != nil {
+			slog.Error("Error watching data source changes", logging.Error(err))
+		}
+	}()
+
+	if err := bybitPoller.Poll(ctx); err != nil {
+		slog.Error("Bybit poller stopped with error", logging.Error(err))
+	}
+	if err := factory.Close(); err != nil {
+		slog.Error("Error closing Bybit quotes writer", logging.Error(err))
+	}
+}
@@ -0,0 +1,159 @@
+package datapoller
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/application/services/quotePollersFactories"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/health"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// RunDataPoller starts a poller for every data source in the catalog that has an
+// implementation, optionally narrowed down with the --only and --exclude flags.
+// Both flags take a comma separated list of data source ids or names. Metrics, liveness
+// and readiness are served on /metrics, /healthz and /readyz of --http-address, an
+// empty address disables them. --staleness is how long a pair may go without a quote
+// before the process is no longer ready. --config is the configuration file, see
+// infrastructure.LoadConfiguration. The data sources come from the catalog selected by
+// its data_sources section, changes of the symbol pairs of a running data source are
+// followed through Postgres notifications or the catalog file.
+func RunDataPoller() {
+	only := flag.String("only", "", "comma separated data source ids or names to poll exclusively")
+	exclude := flag.String("exclude", "", "comma separated data source ids or names to skip")
+	httpAddress := flag.String("http-address", metrics.DefaultAddress, "address of the metrics and health endpoints, empty to disable")
+	staleness := flag.Duration("staleness", health.DefaultStaleness, "time without a quote after which a pair is stale")
+	configPath := infrastructure.ConfigPathFlag()
+	flag.Parse()
+
+	configuration, err := infrastructure.LoadConfiguration(*configPath)
+	if err != nil {
+		log.Fatal("Error loading configuration:", err)
+	}
+	if err := logging.Setup(configuration.Logging); err != nil {
+		log.Fatal("Error setting up logging:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *httpAddress != "" {
+		mux := metrics.NewServeMux()
+		health.Register(mux, health.DefaultMonitor, *staleness)
+		go func() {
+			if err := metrics.Serve(ctx, *httpAddress, mux); err != nil {
+				slog.Error("Error serving metrics", logging.Error(err))
+			}
+		}()
+	}
+
+	catalog, err := quotePollersFactories.OpenDataSources(ctx, configuration)
+	if err != nil {
+		log.Fatal("Error opening the data sources catalog:", err)
+	}
+	defer catalog.Close()
+
+	datasourceRepository := catalog.Repository
+	writers, err := quotePollersFactories.OpenWriters(configuration)
+	if err != nil {
+		log.Fatal("Error creating writers:", err)
+	}
+	factory := quotePollersFactories.NewQuotePollerFactory(datasourceRepository, writers)
+
+	dataSources, err := datasourceRepository.FindAll(ctx)
+	if err != nil {
+		log.Fatal("Error loading data sources:", err)
+	}
+
+	onlyFilter := parseDataSourceFilter(*only)
+	excludeFilter := parseDataSourceFilter(*exclude)
+
+	follower := pollers.NewSymbolPairsFollower(datasourceRepository)
+
+	var wg sync.WaitGroup
+	started := 0
+	for _, dataSource := range dataSources {
+		if len(onlyFilter) > 0 && !matchesDataSourceFilter(onlyFilter, dataSource) {
+			continue
+		}
+		if matchesDataSourceFilter(excludeFilter, dataSource) {
+			slog.Info("Skipping excluded data source", logging.DataSourceKey, dataSource.Name, "id", dataSource.Id)
+			continue
+		}
+
+		poller, err := factory.BuildFor(*dataSource)
+		if errors.Is(err, quotePollersFactories.ErrNoPollerImplementation) {
+			slog.Info("Skipping data source", logging.DataSourceKey, dataSource.Name, logging.Error(err))
+			continue
+		}
+		if err != nil {
+			log.Fatal("Error building poller:", err)
+		}
+
+		slog.Info("Starting poller", logging.DataSourceKey, dataSource.Name, "id", dataSource.Id,
+			"symbol_pairs", len(dataSource.SymbolPairs))
+		follower.Follow(*dataSource, poller)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := poller.Poll(ctx); err != nil {
+				slog.Error("Poller stopped with error", logging.DataSourceKey, dataSource.Name,
+					"id", dataSource.Id, logging.Error(err))
+			}
+		}()
+		started++
+	}
+
+	if started == 0 {
+		log.Fatal("No pollers started")
+	}
+
+	go func() {
+		if err := follower.Run(ctx, catalog.Watcher); err != nil {
+			slog.Error("Error watching data source changes", logging.Error(err))
+		}
+	}()
+
+	wg.Wait()
+	if err := factory.Close(); err != nil {
+		slog.Error("Error closing writers", logging.Error(err))
+	}
+}
+
+func parseDataSourceFilter(value string) []string {
+	var filter []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			filter = append(filter, item)
+		}
+	}
+	return filter
+}
+
+func matchesDataSourceFilter(filter []string, dataSource *entities.DataSource) bool {
+	for _, item := range filter {
+		if id, err := strconv.Atoi(item); err == nil {
+			if id == dataSource.Id {
+				return true
+			}
+			continue
+		}
+		if strings.EqualFold(item, dataSource.Name) {
+			return true
+		}
+	}
+	return false
+}
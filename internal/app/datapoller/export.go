@@ -0,0 +1,86 @@
+package datapoller
+
+import (
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/repositories/file"
+	"DataPoller/internal/common/infrastructure/repositories/postgres"
+	"context"
+	"flag"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// RunExport runs "datapoller export", which dumps the tds catalog of the main database
+// into a catalog file for the file repository. The file holds the logins and passwords
+// of the data sources.
+func RunExport(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("output", "", "catalog file to write, empty for stdout")
+	format := flags.String("format", "", "yaml or json, defaults to json for .json outputs and yaml otherwise")
+	configPath := infrastructure.ConfigPathFlagOn(flags)
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = filerepositories.YamlFormat
+		if strings.EqualFold(filepath.Ext(*output), ".json") {
+			*format = filerepositories.JsonFormat
+		}
+	}
+	if *format != filerepositories.YamlFormat && *format != filerepositories.JsonFormat {
+		log.Fatalf("Unknown catalog format %q", *format)
+	}
+
+	configuration, err := infrastructure.LoadConfiguration(*configPath)
+	if err != nil {
+		log.Fatal("Error loading configuration:", err)
+	}
+	if err := logging.Setup(configuration.Logging); err != nil {
+		log.Fatal("Error setting up logging:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgresrepositories.OpenDatabase(ctx, configuration.MainDatabase)
+	if err != nil {
+		log.Fatal("Error connecting to the main database:", err)
+	}
+	defer db.Close()
+
+	dataSources, err := postgresrepositories.NewPostgresDataSourcesRepository(db,
+		configuration.MainDatabase.QueryTimeout).FindAll(ctx)
+	if err != nil {
+		log.Fatal("Error loading data sources:", err)
+	}
+	catalog := filerepositories.NewCatalog(dataSources)
+
+	var writer io.Writer = os.Stdout
+	var file *os.File
+	if *output != "" {
+		// Only the owner may read the file, it holds the data source passwords.
+		file, err = os.OpenFile(*output, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+		if err != nil {
+			log.Fatal("Error creating catalog file:", err)
+		}
+		writer = file
+	}
+
+	if err := filerepositories.WriteCatalog(writer, catalog, *format); err != nil {
+		log.Fatal("Error writing catalog:", err)
+	}
+	if file != nil {
+		// Some file systems only report failed writes on close.
+		if err := file.Close(); err != nil {
+			log.Fatal("Error closing catalog file:", err)
+		}
+		slog.Info("Exported data sources", "path", *output, "data_sources", len(catalog.DataSources),
+			"symbol_pairs", len(catalog.SymbolPairs))
+	}
+}
@@ -0,0 +1,128 @@
+package datapoller
+
+import (
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/migrations"
+	"DataPoller/internal/common/infrastructure/repositories/postgres"
+	"DataPoller/internal/common/infrastructure/repositories/quest"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage: datapoller migrate up|down|status [flags]
+
+  up      applies the pending migrations
+  down    reverts the --steps latest applied migrations
+  status  lists the migrations and when they were applied
+
+`
+
+// RunMigrate runs "datapoller migrate", which creates and evolves the tds catalog of
+// the main database and the tables of QuestDB, see the migrations package. --target
+// narrows the command down to the postgres or the quest migrations. up fails for a
+// QuestDB table that the line protocol created before with other columns, which has to
+// be converted by hand as the migrations package describes.
+func RunMigrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	target := flags.String("target", "", "migrations to run, "+strings.Join(migrations.Targets, " or ")+", empty for all")
+	steps := flags.Int("steps", 1, "number of migrations down reverts")
+	configPath := infrastructure.ConfigPathFlagOn(flags)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), migrateUsage)
+		flags.PrintDefaults()
+	}
+
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	command := args[0]
+	flags.Parse(args[1:])
+
+	if command != "up" && command != "down" && command != "status" {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *target != "" && !slices.Contains(migrations.Targets, *target) {
+		log.Fatalf("Unknown migration target %q", *target)
+	}
+	if *steps < 1 {
+		log.Fatal("--steps must be at least 1")
+	}
+
+	configuration, err := infrastructure.LoadConfiguration(*configPath)
+	if err != nil {
+		log.Fatal("Error loading configuration:", err)
+	}
+	if err := logging.Setup(configuration.Logging); err != nil {
+		log.Fatal("Error setting up logging:", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	db, err := postgresrepositories.OpenDatabase(ctx, configuration.MainDatabase)
+	if err != nil {
+		log.Fatal("Error connecting to the main database:", err)
+	}
+	defer db.Close()
+
+	databases := make(map[string]*sql.DB)
+	if *target == "" || *target == migrations.PostgresTarget {
+		databases[migrations.PostgresTarget] = db
+	}
+	if *target == "" || *target == migrations.QuestTarget {
+		questDb, err := questrepositories.OpenDatabase(ctx, configuration.TimeSeriesDatabase)
+		if err != nil {
+			log.Fatal("Error connecting to QuestDB:", err)
+		}
+		defer questDb.Close()
+		databases[migrations.QuestTarget] = questDb
+	}
+
+	migrator, err := migrations.NewMigrator(db, databases)
+	if err != nil {
+		log.Fatal("Error loading migrations:", err)
+	}
+
+	switch command {
+	case "up":
+		err = migrator.Up(ctx)
+	case "down":
+		err = migrator.Down(ctx, *steps)
+	case "status":
+		err = printMigrationStatus(ctx, migrator)
+	}
+	if err != nil {
+		log.Fatal("Error running migrations:", err)
+	}
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "TARGET\tVERSION\tNAME\tAPPLIED")
+	for _, status := range statuses {
+		applied := "pending"
+		if status.Applied() {
+			applied = status.AppliedAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(writer, "%s\t%04d\t%s\t%s\n", status.Target, status.Version, status.Name, applied)
+	}
+	return writer.Flush()
+}
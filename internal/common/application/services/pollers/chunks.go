@@ -0,0 +1,51 @@
+package pollers
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"context"
+	"errors"
+	"sync"
+)
+
+// ChunkSymbolPairs splits pairs into chunks of at most chunkSize pairs, one per
+// connection. A chunkSize of 0 keeps all pairs in a single chunk.
+func ChunkSymbolPairs(pairs []entities.SymbolPair, chunkSize int) [][]entities.SymbolPair {
+	if len(pairs) == 0 {
+		return nil
+	}
+	if chunkSize <= 0 {
+		return [][]entities.SymbolPair{pairs}
+	}
+
+	var chunks [][]entities.SymbolPair
+	totalPairs := len(pairs)
+	for i := 0; i < totalPairs; i += chunkSize {
+		end := i + chunkSize
+		if end > totalPairs {
+			end = totalPairs
+		}
+
+		chunks = append(chunks, pairs[i:end])
+	}
+	return chunks
+}
+
+// PollChunks runs poll for every chunk concurrently and waits until all of them have
+// returned. A failing chunk does not stop the others. Chunks are numbered from 0 in
+// the order they were given, which gives every connection a stable id.
+func PollChunks(ctx context.Context, chunks [][]entities.SymbolPair,
+	poll func(ctx context.Context, chunkId int, pairs []entities.SymbolPair) error) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(chunks))
+
+	for i, chunk := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = poll(ctx, i, chunk)
+		}()
+	}
+
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,434 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// binanceDepthSnapshotPath serves the REST snapshots the @depth diff events are
+	// applied to.
+	binanceDepthSnapshotPath  = "/api/v3/depth"
+	binanceDepthSnapshotLimit = 1000
+	// binanceSnapshotInterval spaces the snapshot requests of a poller. A snapshot of
+	// binanceDepthSnapshotLimit levels weighs 50, so they take half of the request
+	// weight Binance allows an IP per minute.
+	binanceSnapshotInterval = time.Second
+	// binanceRateLimitedDelay is how long to wait after a 429 without Retry-After.
+	binanceRateLimitedDelay = time.Minute
+	// binanceBannedDelay is how long to wait after a 418 without Retry-After. Bans last
+	// from 2 minutes to 3 days and grow with every request sent during one.
+	binanceBannedDelay = 10 * time.Minute
+	// binanceMaxBufferedEvents caps the diff events a book buffers while its snapshot is
+	// fetched, 100 seconds of @depth@100ms.
+	binanceMaxBufferedEvents = 1000
+)
+
+// errBinanceBanned means Binance answered with 418: the IP is banned for having kept
+// sending requests after a 429, and every request sent before the ban ends extends it.
+var errBinanceBanned = errors.New("IP banned by Binance")
+
+var binanceHttpClient = &http.Client{Timeout: 10 * time.Second}
+
+// binanceRestHosts maps the stream hosts whose REST API is not on the host named
+// "api." instead of "stream.".
+var binanceRestHosts = map[string]string{
+	"data-stream.binance.vision":    "data-api.binance.vision",
+	"stream.testnet.binance.vision": "testnet.binance.vision",
+}
+
+// binanceDepthSnapshotUrl returns the snapshot endpoint of the REST API that belongs to
+// the stream the data source connects to, e.g. https://api.binance.com/api/v3/depth for
+// wss://stream.binance.com:9443/ws. Hosts without a known REST host, such as a local
+// test server, serve both on the same address.
+func binanceDepthSnapshotUrl(connectionString string) (string, error) {
+	streamUrl, err := url.Parse(connectionString)
+	if err != nil {
+		return "", fmt.Errorf("invalid connection string: %w", err)
+	}
+
+	restUrl := url.URL{Scheme: "https", Host: streamUrl.Host, Path: binanceDepthSnapshotPath}
+	if streamUrl.Scheme == "ws" {
+		restUrl.Scheme = "http"
+	}
+
+	host := streamUrl.Hostname()
+	if restHost, found := binanceRestHosts[host]; found {
+		restUrl.Host = restHost
+	} else if restHost, found := strings.CutPrefix(host, "stream."); found {
+		restUrl.Host = "api." + restHost
+	}
+
+	return restUrl.String(), nil
+}
+
+/*{
+  "e": "depthUpdate",
+  "E": 1672515782136,
+  "s": "BNBBTC",
+  "U": 157,
+  "u": 160,
+  "b": [["0.0024", "10"]],
+  "a": [["0.0026", "100"]]
+}*/
+
+type BinanceDepthMessage struct {
+	EventType     string     `json:"e"`
+	EventTime     int64      `json:"E"`
+	Symbol        string     `json:"s"`
+	FirstUpdateId int64      `json:"U"`
+	FinalUpdateId int64      `json:"u"`
+	Bids          [][]string `json:"b"`
+	Asks          [][]string `json:"a"`
+}
+
+type BinanceDepthSnapshot struct {
+	LastUpdateId int64      `json:"lastUpdateId"`
+	Bids         [][]string `json:"bids"`
+	Asks         [][]string `json:"asks"`
+}
+
+// binanceOrderBook keeps a local book in sync the way Binance documents it: diff events
+// are buffered while a REST snapshot is fetched, events the snapshot already contains
+// are dropped and every later event has to continue where the previous one ended.
+type binanceOrderBook struct {
+	mu           sync.Mutex
+	symbol       string
+	book         *pollers.OrderBook
+	synced       bool
+	fetching     bool
+	lastUpdateId int64
+	buffered     []BinanceDepthMessage
+}
+
+// binanceOrderBooks holds the books of one connection by their upper case symbol. A
+// nil *binanceOrderBooks holds no books, for data sources that do not stream them.
+type binanceOrderBooks struct {
+	mu    sync.Mutex
+	books map[string]*binanceOrderBook
+}
+
+func newBinanceOrderBooks(pairs []entities.SymbolPair) *binanceOrderBooks {
+	books := &binanceOrderBooks{books: make(map[string]*binanceOrderBook)}
+	books.add(pairs)
+	return books
+}
+
+func binanceBookSymbol(pair entities.SymbolPair) string {
+	return strings.ToUpper(pair.BaseSymbol.Name + pair.QuoteSymbol.Name)
+}
+
+// add starts out of sync books for the pairs, they sync with their first depth update.
+func (books *binanceOrderBooks) add(pairs []entities.SymbolPair) {
+	if books == nil {
+		return
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	for _, pair := range pairs {
+		symbol := binanceBookSymbol(pair)
+		books.books[symbol] = &binanceOrderBook{symbol: symbol, book: pollers.NewOrderBook(pair)}
+	}
+}
+
+func (books *binanceOrderBooks) remove(pairs []entities.SymbolPair) {
+	if books == nil {
+		return
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	for _, pair := range pairs {
+		delete(books.books, binanceBookSymbol(pair))
+	}
+}
+
+func (books *binanceOrderBooks) get(symbol string) (*binanceOrderBook, bool) {
+	if books == nil {
+		return nil, false
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	book, found := books.books[symbol]
+	return book, found
+}
+
+// reset marks every book out of sync. A new connection has missed events, so the books
+// are rebuilt from a fresh snapshot.
+func (books *binanceOrderBooks) reset() {
+	if books == nil {
+		return
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	for _, book := range books.books {
+		book.mu.Lock()
+		book.synced = false
+		book.buffered = nil
+		book.book.Clear()
+		book.mu.Unlock()
+	}
+}
+
+func (books *binanceOrderBooks) snapshots(depth int, timeStamp time.Time) []entities.OrderBookSnapshot {
+	books.mu.Lock()
+	defer books.mu.Unlock()
+
+	var snapshots []entities.OrderBookSnapshot
+	for _, book := range books.books {
+		book.mu.Lock()
+		if book.synced {
+			snapshots = append(snapshots, book.book.Snapshot(depth, timeStamp))
+		}
+		book.mu.Unlock()
+	}
+	return snapshots
+}
+
+func (binancePoller *BinancePoller) handleDepth(ctx context.Context, message []byte, books *binanceOrderBooks) {
+	var depthMsg BinanceDepthMessage
+	if err := json.Unmarshal(message, &depthMsg); err != nil {
+		binancePoller.logger.Warn("Error unmarshaling Binance depth update", logging.Error(err))
+		binancePoller.feed.ParseError(entities.SymbolPair{})
+		return
+	}
+
+	book, found := books.get(depthMsg.Symbol)
+	if !found {
+		binancePoller.logger.Warn("Depth update for unknown Binance symbol", "symbol", depthMsg.Symbol)
+		return
+	}
+
+	book.mu.Lock()
+	defer book.mu.Unlock()
+
+	if book.synced {
+		err := book.apply(depthMsg)
+		if err == nil {
+			return
+		}
+		binancePoller.logger.Warn("Resyncing Binance order book", logging.SymbolPair(book.book.Pair), logging.Error(err))
+		book.synced = false
+		book.book.Clear()
+	}
+
+	if n := len(book.buffered); n > 0 && depthMsg.FirstUpdateId > book.buffered[n-1].FinalUpdateId+1 {
+		// No snapshot can be joined with events that have a gap, one taken from here on
+		// contains the missed events and only needs the ones that follow.
+		binancePoller.logger.Warn("Dropping buffered Binance depth updates after a gap",
+			logging.SymbolPair(book.book.Pair), "events", n)
+		book.buffered = nil
+	} else if n >= binanceMaxBufferedEvents {
+		// A snapshot taken from here on contains the dropped events, it only needs the
+		// ones buffered after them.
+		binancePoller.logger.Warn("Dropping buffered Binance depth updates, snapshot took too long",
+			logging.SymbolPair(book.book.Pair), "events", n)
+		book.buffered = nil
+	}
+	book.buffered = append(book.buffered, depthMsg)
+	if !book.fetching {
+		book.fetching = true
+		go binancePoller.syncOrderBook(ctx, book)
+	}
+}
+
+// syncOrderBook fetches snapshots until one can be joined with the buffered events,
+// backing off between failed attempts.
+func (binancePoller *BinancePoller) syncOrderBook(ctx context.Context, book *binanceOrderBook) {
+	attempt := 0
+
+	for {
+		snapshot, err := binancePoller.snapshots.fetch(ctx, book.symbol)
+		if err == nil {
+			book.mu.Lock()
+			err = book.applySnapshot(snapshot)
+			if err == nil {
+				book.fetching = false
+				book.mu.Unlock()
+				binancePoller.logger.Info("Synced Binance order book", logging.SymbolPair(book.book.Pair),
+					"last_update_id", snapshot.LastUpdateId)
+				return
+			}
+			book.mu.Unlock()
+		}
+
+		if ctx.Err() != nil {
+			book.mu.Lock()
+			book.fetching = false
+			book.mu.Unlock()
+			return
+		}
+
+		attempt++
+		delay := websockets.DefaultBackoffPolicy.Interval(attempt)
+		binancePoller.logger.Warn("Error syncing Binance order book, retrying", logging.SymbolPair(book.book.Pair),
+			logging.Error(err), "delay", delay.Round(time.Millisecond), "attempt", attempt)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+// applySnapshot replaces the book with the snapshot and applies the buffered events
+// that follow it. It fails when the snapshot is older than the first buffered event.
+func (book *binanceOrderBook) applySnapshot(snapshot BinanceDepthSnapshot) error {
+	book.book.Clear()
+	book.lastUpdateId = snapshot.LastUpdateId
+
+	if err := applyBinanceLevels(book.book.Bids, snapshot.Bids); err != nil {
+		return err
+	}
+	if err := applyBinanceLevels(book.book.Asks, snapshot.Asks); err != nil {
+		return err
+	}
+
+	for _, depthMsg := range book.buffered {
+		if err := book.apply(depthMsg); err != nil {
+			return err
+		}
+	}
+
+	book.buffered = nil
+	book.synced = true
+	return nil
+}
+
+// apply applies a diff event. Events the book already contains are ignored, an event
+// that does not start right after the last applied one means events were missed.
+func (book *binanceOrderBook) apply(depthMsg BinanceDepthMessage) error {
+	if depthMsg.FinalUpdateId <= book.lastUpdateId {
+		return nil
+	}
+	if depthMsg.FirstUpdateId > book.lastUpdateId+1 {
+		return fmt.Errorf("missed updates %d to %d", book.lastUpdateId+1, depthMsg.FirstUpdateId-1)
+	}
+
+	if err := applyBinanceLevels(book.book.Bids, depthMsg.Bids); err != nil {
+		return err
+	}
+	if err := applyBinanceLevels(book.book.Asks, depthMsg.Asks); err != nil {
+		return err
+	}
+
+	book.lastUpdateId = depthMsg.FinalUpdateId
+	return nil
+}
+
+func applyBinanceLevels(side *pollers.OrderBookSide, levels [][]string) error {
+	for _, level := range levels {
+		if len(level) < 2 {
+			return fmt.Errorf("invalid level %v", level)
+		}
+		price, err := entities.ParseDecimal(level[0])
+		if err != nil {
+			return err
+		}
+		quantity, err := entities.ParseDecimal(level[1])
+		if err != nil {
+			return err
+		}
+		side.Set(price, quantity)
+	}
+	return nil
+}
+
+// binanceSnapshotFetcher sends the snapshot requests of all books of a poller one at a
+// time, so a reconnect that desyncs every book does not burst into the request weight
+// limit of Binance. It waits for the Retry-After of a 429, and of a 418 until the ban
+// is over.
+type binanceSnapshotFetcher struct {
+	url    string
+	logger *slog.Logger
+	mu     sync.Mutex
+	// next is the earliest time the next request may be sent.
+	next time.Time
+}
+
+func newBinanceSnapshotFetcher(url string, logger *slog.Logger) *binanceSnapshotFetcher {
+	return &binanceSnapshotFetcher{url: url, logger: logger}
+}
+
+func (fetcher *binanceSnapshotFetcher) fetch(ctx context.Context, symbol string) (BinanceDepthSnapshot, error) {
+	fetcher.mu.Lock()
+	defer fetcher.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return BinanceDepthSnapshot{}, ctx.Err()
+	case <-time.After(time.Until(fetcher.next)):
+	}
+
+	snapshot, retryAfter, err := fetchBinanceDepthSnapshot(ctx, fetcher.url, symbol)
+	fetcher.next = time.Now().Add(max(retryAfter, binanceSnapshotInterval))
+
+	if errors.Is(err, errBinanceBanned) {
+		fetcher.logger.Error("Pausing Binance order book snapshots until the ban is over", logging.Error(err),
+			"until", fetcher.next)
+	}
+	return snapshot, err
+}
+
+// fetchBinanceDepthSnapshot fetches the snapshot of the symbol. When Binance rate limits
+// the request it also returns how long to wait before the next one.
+func fetchBinanceDepthSnapshot(ctx context.Context, snapshotUrl string, symbol string) (BinanceDepthSnapshot,
+	time.Duration, error) {
+	var snapshot BinanceDepthSnapshot
+
+	query := url.Values{}
+	query.Set("symbol", symbol)
+	query.Set("limit", fmt.Sprint(binanceDepthSnapshotLimit))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, snapshotUrl+"?"+query.Encode(), nil)
+	if err != nil {
+		return snapshot, 0, err
+	}
+
+	response, err := binanceHttpClient.Do(request)
+	if err != nil {
+		return snapshot, 0, err
+	}
+	defer response.Body.Close()
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		retryAfter := binanceRetryAfter(response, binanceRateLimitedDelay)
+		return snapshot, retryAfter, fmt.Errorf("depth snapshot request rate limited, retrying after %s", retryAfter)
+	case http.StatusTeapot:
+		retryAfter := binanceRetryAfter(response, binanceBannedDelay)
+		return snapshot, retryAfter, fmt.Errorf("%w, retrying after %s", errBinanceBanned, retryAfter)
+	default:
+		return snapshot, 0, fmt.Errorf("depth snapshot request failed with status %s", response.Status)
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&snapshot)
+	return snapshot, 0, err
+}
+
+// binanceRetryAfter returns the Retry-After of the response, which Binance sends in
+// seconds, or fallback without one.
+func binanceRetryAfter(response *http.Response, fallback time.Duration) time.Duration {
+	seconds, err := strconv.Atoi(response.Header.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return time.Duration(seconds) * time.Second
+}
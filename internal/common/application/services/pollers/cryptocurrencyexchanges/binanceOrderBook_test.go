@@ -0,0 +1,210 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"testing"
+)
+
+var binanceTestPair = entities.SymbolPair{
+	Id:          1,
+	BaseSymbol:  entities.Symbol{Id: 1, Name: "BTC"},
+	QuoteSymbol: entities.Symbol{Id: 2, Name: "USDT"},
+}
+
+// binanceTestDepth is a depth update of binanceTestPair setting one bid at a price
+// named after its final update id.
+func binanceTestDepth(firstUpdateId int64, finalUpdateId int64) BinanceDepthMessage {
+	return BinanceDepthMessage{
+		EventType:     "depthUpdate",
+		Symbol:        "BTCUSDT",
+		FirstUpdateId: firstUpdateId,
+		FinalUpdateId: finalUpdateId,
+		Bids:          [][]string{{strconv.FormatInt(finalUpdateId, 10), "1"}},
+	}
+}
+
+func TestBinanceOrderBookApplySnapshot(t *testing.T) {
+	tests := []struct {
+		name         string
+		lastUpdateId int64
+		buffered     []BinanceDepthMessage
+		wantErr      bool
+		wantLastId   int64
+	}{
+		{name: "nothing buffered", lastUpdateId: 100, wantLastId: 100},
+		{
+			name:         "events contained in the snapshot are dropped",
+			lastUpdateId: 100,
+			buffered:     []BinanceDepthMessage{binanceTestDepth(90, 95), binanceTestDepth(96, 100)},
+			wantLastId:   100,
+		},
+		{
+			name:         "first event straddles the snapshot",
+			lastUpdateId: 100,
+			buffered:     []BinanceDepthMessage{binanceTestDepth(96, 103), binanceTestDepth(104, 107)},
+			wantLastId:   107,
+		},
+		{
+			name:         "first event starts right after the snapshot",
+			lastUpdateId: 100,
+			buffered:     []BinanceDepthMessage{binanceTestDepth(101, 103)},
+			wantLastId:   103,
+		},
+		{
+			name:         "snapshot older than the buffered events",
+			lastUpdateId: 100,
+			buffered:     []BinanceDepthMessage{binanceTestDepth(102, 103)},
+			wantErr:      true,
+		},
+		{
+			name:         "gap between buffered events",
+			lastUpdateId: 100,
+			buffered:     []BinanceDepthMessage{binanceTestDepth(96, 103), binanceTestDepth(105, 107)},
+			wantErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			books := newBinanceOrderBooks([]entities.SymbolPair{binanceTestPair})
+			book, _ := books.get("BTCUSDT")
+			book.buffered = test.buffered
+
+			err := book.applySnapshot(BinanceDepthSnapshot{
+				LastUpdateId: test.lastUpdateId,
+				Bids:         [][]string{{"1", "1"}},
+				Asks:         [][]string{{"2", "1"}},
+			})
+			if test.wantErr {
+				if err == nil {
+					t.Fatal("applySnapshot() succeeded, want an error")
+				}
+				if book.synced {
+					t.Error("book is synced after a failed snapshot")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applySnapshot() failed: %v", err)
+			}
+			if !book.synced {
+				t.Error("book is not synced")
+			}
+			if book.lastUpdateId != test.wantLastId {
+				t.Errorf("lastUpdateId = %d, want %d", book.lastUpdateId, test.wantLastId)
+			}
+			if len(book.buffered) != 0 {
+				t.Errorf("%d events still buffered", len(book.buffered))
+			}
+		})
+	}
+}
+
+func TestBinanceHandleDepthBuffering(t *testing.T) {
+	contiguous := func(count int) []BinanceDepthMessage {
+		events := make([]BinanceDepthMessage, count)
+		for i := range events {
+			events[i] = binanceTestDepth(int64(2*i+1), int64(2*i+2))
+		}
+		return events
+	}
+
+	tests := []struct {
+		name         string
+		synced       bool
+		lastUpdateId int64
+		events       []BinanceDepthMessage
+		// wantBuffered are the first update ids of the events left in the buffer.
+		wantBuffered []int64
+		wantSynced   bool
+		wantLastId   int64
+	}{
+		{
+			name:         "contiguous events are buffered",
+			events:       contiguous(3),
+			wantBuffered: []int64{1, 3, 5},
+		},
+		{
+			name:         "gap drops the buffer",
+			events:       []BinanceDepthMessage{binanceTestDepth(1, 2), binanceTestDepth(3, 4), binanceTestDepth(7, 8)},
+			wantBuffered: []int64{7},
+		},
+		{
+			name:         "overlapping events are kept",
+			events:       []BinanceDepthMessage{binanceTestDepth(1, 4), binanceTestDepth(3, 6)},
+			wantBuffered: []int64{1, 3},
+		},
+		{
+			name:         "full buffer is dropped",
+			events:       contiguous(binanceMaxBufferedEvents + 1),
+			wantBuffered: []int64{2*binanceMaxBufferedEvents + 1},
+		},
+		{
+			name:         "synced book applies events",
+			synced:       true,
+			lastUpdateId: 10,
+			events:       []BinanceDepthMessage{binanceTestDepth(11, 12), binanceTestDepth(13, 14)},
+			wantSynced:   true,
+			wantLastId:   14,
+		},
+		{
+			name:         "synced book ignores old events",
+			synced:       true,
+			lastUpdateId: 10,
+			events:       []BinanceDepthMessage{binanceTestDepth(5, 10)},
+			wantSynced:   true,
+			wantLastId:   10,
+		},
+		{
+			name:         "synced book resyncs after missed events",
+			synced:       true,
+			lastUpdateId: 10,
+			events:       []BinanceDepthMessage{binanceTestDepth(11, 12), binanceTestDepth(15, 16)},
+			wantBuffered: []int64{15},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			binancePoller := &BinancePoller{
+				feed:   metrics.NewFeed("binance test"),
+				logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			books := newBinanceOrderBooks([]entities.SymbolPair{binanceTestPair})
+			book, _ := books.get("BTCUSDT")
+			book.synced = test.synced
+			book.lastUpdateId = test.lastUpdateId
+			// A snapshot is already being fetched, so no request is sent.
+			book.fetching = true
+
+			for _, event := range test.events {
+				message, err := json.Marshal(event)
+				if err != nil {
+					t.Fatal(err)
+				}
+				binancePoller.handleDepth(context.Background(), message, books)
+			}
+
+			if book.synced != test.wantSynced {
+				t.Errorf("synced = %v, want %v", book.synced, test.wantSynced)
+			}
+			if test.wantSynced && book.lastUpdateId != test.wantLastId {
+				t.Errorf("lastUpdateId = %d, want %d", book.lastUpdateId, test.wantLastId)
+			}
+			var buffered []int64
+			for _, event := range book.buffered {
+				buffered = append(buffered, event.FirstUpdateId)
+			}
+			if !slices.Equal(buffered, test.wantBuffered) {
+				t.Errorf("buffered events starting at %v, want %v", buffered, test.wantBuffered)
+			}
+		})
+	}
+}
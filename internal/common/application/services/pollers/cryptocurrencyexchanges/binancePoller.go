@@ -2,22 +2,54 @@ package cryptocurrencyexchanges
 
 import (
 	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/consts"
 	"DataPoller/internal/common/domain/entities"
 	"DataPoller/internal/common/domain/repositories"
-	questrepositories "DataPoller/internal/common/infrastructure/repositories/quest"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"context"
+	"log/slog"
+	"sync"
 	"time"
 
 	"encoding/json"
 	"fmt"
-	"log"
 	"strings"
+)
+
+// Binance drops every stream connection after 24 hours, pings every 20 seconds and
+// disconnects clients that have not answered with a pong within a minute.
+var binanceConnectionOptions = websockets.Options{
+	Backoff:      websockets.DefaultBackoffPolicy,
+	ReadTimeout:  time.Minute,
+	PingInterval: 30 * time.Second,
+	MaxLifetime:  23 * time.Hour,
+}
+
+// binanceMaxStreamsPerConnection is the number of streams Binance lets one connection
+// subscribe.
+const binanceMaxStreamsPerConnection = 1024
 
-	"github.com/gorilla/websocket"
+// Ids of the requests sent to Binance, which its responses carry.
+const (
+	binanceSubscribeId = iota + 1
+	binanceUnsubscribeId
+	binanceLiveSubscribeId
+	binanceLiveUnsubscribeId
 )
 
 type BinancePoller struct {
 	dataSource         entities.DataSource
 	cryptoQuotesWriter repositories.CryptoQuotesWriter
+	cryptoTradesWriter repositories.CryptoTradesWriter
+	orderBookWriter    repositories.OrderBookWriter
+	feed               *metrics.Feed
+	logger             *slog.Logger
+	chunks             *pollers.LiveChunks
+	snapshots          *binanceSnapshotFetcher
+	// pairsMu guards dataSource.SymbolPairs, which UpdateSymbolPairs replaces.
+	pairsMu sync.RWMutex
 }
 
 type BinanceSubscribeMessage struct {
@@ -27,8 +59,22 @@ type BinanceSubscribeMessage struct {
 }
 
 type BinanceSubscriptionResponse struct {
-	Result interface{} `json:"result"`
-	Id     int         `json:"id"`
+	Result interface{}           `json:"result"`
+	Error  *BinanceResponseError `json:"error"`
+	Id     int                   `json:"id"`
+}
+
+type BinanceResponseError struct {
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+// BinanceEventMessage holds the fields every stream event has. Both keys are declared,
+// otherwise "E" would case-insensitively match the "e" field.
+type BinanceEventMessage struct {
+	EventType string `json:"e"`
+	EventTime int64  `json:"E"`
+	Symbol    string `json:"s"`
 }
 
 type BinanceTickerMessage struct {
@@ -57,132 +103,441 @@ type BinanceTickerMessage struct {
 	TotalNumberOfTrades int64  `json:"n"`
 }
 
+// BinanceTradeMessage is a @trade or @aggTrade event. Trade events carry "t" instead of
+// "a", "f" and "l".
+type BinanceTradeMessage struct {
+	EventType        string `json:"e"`
+	EventTime        int64  `json:"E"`
+	Symbol           string `json:"s"`
+	TradeId          int64  `json:"t"`
+	AggregateTradeId int64  `json:"a"`
+	Price            string `json:"p"`
+	Quantity         string `json:"q"`
+	FirstTradeId     int64  `json:"f"`
+	LastTradeId      int64  `json:"l"`
+	TradeTime        int64  `json:"T"`
+	IsBuyerMaker     bool   `json:"m"`
+	Ignore           bool   `json:"M"`
+}
+
+func init() {
+	pollers.Register(consts.Binance, NewBinancePoller)
+}
+
 func NewBinancePoller(dataSource entities.DataSource,
-	cryptoQuotesWriter repositories.CryptoQuotesWriter) pollers.QuotePoller {
-	return &BinancePoller{dataSource: dataSource, cryptoQuotesWriter: cryptoQuotesWriter}
+	writers pollers.Writers) pollers.QuotePoller {
+	binancePoller := &BinancePoller{
+		dataSource:         dataSource,
+		cryptoQuotesWriter: writers.CryptoQuotes,
+		cryptoTradesWriter: writers.CryptoTrades,
+		orderBookWriter:    writers.OrderBooks,
+		feed:               metrics.NewFeed(dataSource.Name),
+		logger:             slog.With(logging.DataSourceKey, dataSource.Name),
+	}
+
+	depthSnapshotUrl, err := binanceDepthSnapshotUrl(dataSource.ConnectionString)
+	if err != nil {
+		// Dialing the connection string fails the same way, so no book is ever fetched.
+		binancePoller.logger.Error("Error deriving the Binance depth snapshot URL", logging.Error(err))
+	}
+	binancePoller.snapshots = newBinanceSnapshotFetcher(depthSnapshotUrl, binancePoller.logger)
+
+	binancePoller.chunks = pollers.NewLiveChunks(dataSource.SymbolPairs, binancePoller.pairsPerConnection(),
+		binancePoller.newChunk)
+	return binancePoller
+}
+
+// pairsPerConnection returns how many pairs fit on one connection. The rate limit of
+// the data source counts streams, like the limit of Binance, and every pair takes one
+// stream per streamed channel.
+func (binancePoller *BinancePoller) pairsPerConnection() int {
+	streams := binancePoller.dataSource.RateLimit
+	if streams == 0 || streams > binanceMaxStreamsPerConnection {
+		streams = binanceMaxStreamsPerConnection
+	}
+	return max(streams/max(len(binancePoller.streamNames()), 1), 1)
+}
+
+func (binancePoller *BinancePoller) Poll(ctx context.Context) error {
+	return binancePoller.chunks.Run(ctx)
 }
 
-func (binancePoller *BinancePoller) Poll() {
-	symbolPairs := binancePoller.dataSource.SymbolPairs
-	rateLimit := binancePoller.dataSource.RateLimit
+// UpdateSymbolPairs subscribes the streams of added pairs and unsubscribes the streams
+// of removed pairs on the running connections.
+func (binancePoller *BinancePoller) UpdateSymbolPairs(pairs []entities.SymbolPair) {
+	binancePoller.pairsMu.Lock()
+	binancePoller.dataSource.SymbolPairs = pairs
+	binancePoller.pairsMu.Unlock()
 
-	if rateLimit == 0 {
-		go binancePoller.pollSymbolChunk(symbolPairs)
-		select {}
+	binancePoller.chunks.Update(pairs)
+}
+
+func (binancePoller *BinancePoller) symbolPairs() []entities.SymbolPair {
+	binancePoller.pairsMu.RLock()
+	defer binancePoller.pairsMu.RUnlock()
+	return binancePoller.dataSource.SymbolPairs
+}
+
+// binanceChunk is one connection and the pairs it streams.
+type binanceChunk struct {
+	name   string
+	logger *slog.Logger
+	books  *binanceOrderBooks
+	// mu guards the pairs and the connection they were last subscribed on. Subscribe
+	// and live changes hold it, so a change is either sent on the current connection
+	// or picked up by the next Subscribe.
+	mu      sync.Mutex
+	pairs   []entities.SymbolPair
+	current *websockets.Conn
+}
+
+func (binancePoller *BinancePoller) newChunk(chunkId int, pairs []entities.SymbolPair) pollers.LiveChunk {
+	chunk := &binanceChunk{
+		name:  fmt.Sprintf("Binance conn %d", chunkId),
+		pairs: pairs,
+	}
+	chunk.logger = binancePoller.logger.With(logging.ConnectionIdKey, chunk.name)
+	if binancePoller.dataSource.Streams(entities.OrderBookChannel) {
+		chunk.books = newBinanceOrderBooks(pairs)
 	}
 
-	totalPairs := len(symbolPairs)
-	for i := 0; i < totalPairs; i += rateLimit {
-		end := i + rateLimit
-		if end > totalPairs {
-			end = totalPairs
-		}
+	return pollers.LiveChunk{
+		Run: func(ctx context.Context) error {
+			return binancePoller.pollSymbolChunk(ctx, chunk)
+		},
+		Add: func(pairs []entities.SymbolPair) {
+			binancePoller.changeSymbolPairs(chunk, pairs, nil)
+		},
+		Remove: func(pairs []entities.SymbolPair) {
+			binancePoller.changeSymbolPairs(chunk, nil, pairs)
+		},
+	}
+}
 
-		go binancePoller.pollSymbolChunk(symbolPairs[i:end])
+func (binancePoller *BinancePoller) pollSymbolChunk(ctx context.Context, chunk *binanceChunk) error {
+	if chunk.books != nil {
+		go pollers.WriteOrderBookSnapshots(ctx, binancePoller.orderBookWriter, chunk.books.snapshots)
 	}
 
-	select {}
+	conn := websockets.NewReconnectingConn(
+		chunk.name,
+		binancePoller.dataSource.ConnectionString,
+		binanceConnectionOptions,
+		websockets.Handler{
+			Subscribe: func(ctx context.Context, conn *websockets.Conn) error {
+				chunk.mu.Lock()
+				defer chunk.mu.Unlock()
+
+				chunk.logger.Info("Started Binance connection", logging.SymbolPairs(chunk.pairs))
+				chunk.books.reset()
+				chunk.current = conn
+				binancePoller.feed.SetSubscribedChannels(chunk.name, 0)
+
+				subMsg := BinanceSubscribeMessage{
+					Method: "SUBSCRIBE",
+					Params: binancePoller.streams(chunk.pairs),
+					Id:     binanceSubscribeId,
+				}
+				return conn.WriteJSON(subMsg)
+			},
+			HandleMessage: func(conn *websockets.Conn, message []byte, receivedAt time.Time) error {
+				binancePoller.handleMessage(ctx, chunk, message, receivedAt)
+				return nil
+			},
+			Unsubscribe: func(conn *websockets.Conn) {
+				chunk.mu.Lock()
+				defer chunk.mu.Unlock()
+
+				params := binancePoller.streams(chunk.pairs)
+				unsubMsg := BinanceSubscribeMessage{
+					Method: "UNSUBSCRIBE",
+					Params: params,
+					Id:     binanceUnsubscribeId,
+				}
+				if err := conn.WriteJSON(unsubMsg); err != nil {
+					chunk.logger.Warn("Error sending Binance unsubscribe message", logging.Error(err))
+				}
+				binancePoller.feed.SetSubscribedChannels(chunk.name, 0)
+				chunk.logger.Info("Closed Binance streams", "streams", params)
+			},
+		})
+
+	return conn.Run(ctx)
 }
 
-func (binancePoller *BinancePoller) pollSymbolChunk(pairs []entities.SymbolPair) {
-	conn, _, err := websocket.DefaultDialer.Dial(binancePoller.dataSource.ConnectionString, nil)
-	if err != nil {
-		log.Fatal("Error connecting to Binance WebSocket:", err)
+// changeSymbolPairs adds and removes pairs of the chunk and subscribes or unsubscribes
+// their streams on the current connection. Failed writes are left to the reconnect,
+// which subscribes the changed pairs.
+func (binancePoller *BinancePoller) changeSymbolPairs(chunk *binanceChunk, added []entities.SymbolPair,
+	removed []entities.SymbolPair) {
+	chunk.mu.Lock()
+	defer chunk.mu.Unlock()
+
+	chunk.pairs = append(pollers.RemoveSymbolPairs(chunk.pairs, removed), added...)
+	chunk.books.add(added)
+	chunk.books.remove(removed)
+
+	if chunk.current == nil {
 		return
 	}
-	defer conn.Close()
-	log.Printf("Started Binance conn for pairs: %+v\n", pairs)
 
-	var params []string
+	if len(removed) > 0 {
+		params := binancePoller.streams(removed)
+		unsubMsg := BinanceSubscribeMessage{
+			Method: "UNSUBSCRIBE",
+			Params: params,
+			Id:     binanceLiveUnsubscribeId,
+		}
+		if err := chunk.current.WriteJSON(unsubMsg); err != nil {
+			chunk.logger.Warn("Error sending Binance unsubscribe message", logging.Error(err))
+		} else {
+			chunk.logger.Info("Unsubscribing Binance streams", "streams", params)
+		}
+	}
+
+	if len(added) > 0 {
+		params := binancePoller.streams(added)
+		subMsg := BinanceSubscribeMessage{
+			Method: "SUBSCRIBE",
+			Params: params,
+			Id:     binanceLiveSubscribeId,
+		}
+		if err := chunk.current.WriteJSON(subMsg); err != nil {
+			chunk.logger.Warn("Error sending Binance subscribe message", logging.Error(err))
+		} else {
+			chunk.logger.Info("Subscribing Binance streams", "streams", params)
+		}
+	}
+}
+
+// streams returns the stream names of the pairs for the channels of the data source.
+func (binancePoller *BinancePoller) streams(pairs []entities.SymbolPair) []string {
+	names := binancePoller.streamNames()
+	params := make([]string, 0, len(pairs)*len(names))
 	for _, pair := range pairs {
-		symbolParam := fmt.Sprintf("%s%s@ticker",
-			strings.ToLower(pair.BaseSymbol.Name),
-			strings.ToLower(pair.QuoteSymbol.Name))
-		params = append(params, symbolParam)
+		symbol := strings.ToLower(pair.BaseSymbol.Name + pair.QuoteSymbol.Name)
+		for _, name := range names {
+			params = append(params, symbol+"@"+name)
+		}
 	}
+	return params
+}
 
-	subMsg := BinanceSubscribeMessage{
-		Method: "SUBSCRIBE",
-		Params: params,
-		Id:     1,
+// streamNames returns the Binance streams the data source subscribes for every pair.
+func (binancePoller *BinancePoller) streamNames() []string {
+	var names []string
+	if binancePoller.dataSource.Streams(entities.TickerChannel) {
+		names = append(names, "ticker")
+	}
+	if binancePoller.dataSource.Streams(entities.TradesChannel) {
+		names = append(names, "trade")
 	}
+	if binancePoller.dataSource.Streams(entities.AggregatedTradesChannel) {
+		names = append(names, "aggTrade")
+	}
+	if binancePoller.dataSource.Streams(entities.OrderBookChannel) {
+		names = append(names, "depth@100ms")
+	}
+	return names
+}
 
-	msgJSON, err := json.Marshal(subMsg)
-	if err != nil {
-		log.Fatal("Error marshaling Binance subscription JSON:", err)
+func (binancePoller *BinancePoller) handleMessage(ctx context.Context, chunk *binanceChunk, message []byte,
+	receivedAt time.Time) {
+	var subResp BinanceSubscriptionResponse
+	if err := json.Unmarshal(message, &subResp); err == nil && subResp.Id != 0 {
+		binancePoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+		binancePoller.handleResponse(chunk, subResp)
 		return
 	}
 
-	if err = conn.WriteMessage(websocket.TextMessage, msgJSON); err != nil {
-		log.Fatal("Error sending Binance subscription message:", err)
+	var event BinanceEventMessage
+	if err := json.Unmarshal(message, &event); err != nil {
+		binancePoller.logger.Warn("Error unmarshaling Binance message", logging.Error(err))
+		binancePoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+		binancePoller.feed.ParseError(entities.SymbolPair{})
 		return
 	}
 
-	for {
-		_, message, err := conn.ReadMessage()
-		if err != nil {
-			log.Println("Error reading Binance message:", err)
-			return
-		}
+	// Unknown symbols are counted without a pair.
+	pair, _ := binancePoller.findSymbolPair(event.Symbol, binancePoller.symbolPairs())
+	binancePoller.feed.MessageReceived(pair, receivedAt)
+	if event.EventTime != 0 {
+		metrics.ObserveFeedLatency(binancePoller.dataSource.Name, time.UnixMilli(event.EventTime), receivedAt)
+	}
 
-		var subResp BinanceSubscriptionResponse
-		if err := json.Unmarshal(message, &subResp); err == nil && subResp.Id == 1 {
-			//fmt.Printf("Binance subscription response: %+v\n", subResp)
-			log.Println("Subscribed to Binance WebSocket streams:", params)
-			continue
-		}
+	switch event.EventType {
+	case "24hrTicker":
+		binancePoller.handleTicker(message, pair, receivedAt)
+	case "trade", "aggTrade":
+		binancePoller.handleTrade(message, pair)
+	case "depthUpdate":
+		binancePoller.handleDepth(ctx, message, chunk.books)
+	default:
+		binancePoller.logger.Warn("Unknown Binance event type", "event_type", event.EventType)
+	}
+}
 
-		var tickerMsg BinanceTickerMessage
-		err = json.Unmarshal(message, &tickerMsg)
-		if err != nil {
-			log.Println("Error unmarshaling Binance message:", err)
-			continue
-		}
+// handleResponse counts the streams of the chunk once a subscription request succeeded.
+func (binancePoller *BinancePoller) handleResponse(chunk *binanceChunk, subResp BinanceSubscriptionResponse) {
+	if subResp.Error != nil {
+		chunk.logger.Error("Binance request failed", "id", subResp.Id,
+			"code", subResp.Error.Code, "reason", subResp.Error.Msg)
+		return
+	}
+	if subResp.Id == binanceUnsubscribeId {
+		return
+	}
 
-		quote, err := binancePoller.tickerToCryptoQuote(tickerMsg, binancePoller.dataSource)
-		if err != nil {
-			log.Println("Error converting Binance ticker to quote:", err)
-			continue
-		}
+	chunk.mu.Lock()
+	params := binancePoller.streams(chunk.pairs)
+	chunk.mu.Unlock()
 
-		err = binancePoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
-		if err != nil {
-			log.Println("Error writing Binance quote:", err)
-			continue
-		}
+	if subResp.Id == binanceSubscribeId {
+		chunk.logger.Info("Subscribed to Binance streams", "streams", params)
+	}
+	binancePoller.feed.SetSubscribedChannels(chunk.name, len(params))
+}
 
-		//fmt.Printf("Binance ticker update: %+v\n", tickerMsg)
+func (binancePoller *BinancePoller) handleTicker(message []byte, pair entities.SymbolPair, receivedAt time.Time) {
+	var tickerMsg BinanceTickerMessage
+	err := json.Unmarshal(message, &tickerMsg)
+	if err != nil {
+		binancePoller.logger.Warn("Error unmarshaling Binance ticker", logging.SymbolPair(pair), logging.Error(err))
+		binancePoller.feed.ParseError(pair)
+		return
 	}
+
+	quote, err := binancePoller.tickerToCryptoQuote(tickerMsg, binancePoller.symbolPairs())
+	if err != nil {
+		binancePoller.logger.Warn("Error converting Binance ticker to quote", logging.SymbolPair(pair), logging.Error(err))
+		binancePoller.feed.ParseError(pair)
+		return
+	}
+	quote.ReceivedAt = receivedAt
+
+	err = binancePoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
+	if err != nil {
+		binancePoller.logger.Error("Error writing Binance quote", logging.SymbolPair(pair), logging.Error(err))
+		binancePoller.feed.WriteError(pair)
+		return
+	}
+	binancePoller.feed.QuoteWritten(pair)
+
+	binancePoller.logger.Debug("Wrote Binance quote", logging.SymbolPair(pair), "rate", quote.Rate)
 }
 
-func (binancePoller *BinancePoller) tickerToCryptoQuote(ticker BinanceTickerMessage, dataSource entities.DataSource) (entities.CryptoQuote, error) {
+func (binancePoller *BinancePoller) tickerToCryptoQuote(ticker BinanceTickerMessage, pairs []entities.SymbolPair) (entities.CryptoQuote, error) {
 	var quote entities.CryptoQuote
 
-	pair, err := binancePoller.findSymbolPair(ticker.Symbol, dataSource.SymbolPairs)
+	pair, err := binancePoller.findSymbolPair(ticker.Symbol, pairs)
 	if err != nil {
 		return quote, err
 	}
 
-	rate, _ := questrepositories.ToDatabaseRate(ticker.LastPrice)
-	openRate, _ := questrepositories.ToDatabaseRate(ticker.OpenPrice)
-	highRate, _ := questrepositories.ToDatabaseRate(ticker.HighPrice)
-	lowRate, _ := questrepositories.ToDatabaseRate(ticker.LowPrice)
+	rate, err := pair.ParsePrice(ticker.LastPrice)
+	if err != nil {
+		return quote, fmt.Errorf("invalid last price: %w", err)
+	}
+	fields := tickerFields{pair: pair}
+	openRate := fields.price("open price", ticker.OpenPrice)
+	highRate := fields.price("high price", ticker.HighPrice)
+	lowRate := fields.price("low price", ticker.LowPrice)
 	closeRate := rate
-	volume, _ := questrepositories.ToDatabaseRate(ticker.Volume)
+	volume := fields.quantity("volume", ticker.Volume)
+	quoteVolume := fields.quantity("quote volume", ticker.QuoteVolume)
+	bidRate := fields.price("bid price", ticker.BestBidPrice)
+	bidSize := fields.quantity("bid size", ticker.BestBidQuantity)
+	askRate := fields.price("ask price", ticker.BestAskPrice)
+	askSize := fields.quantity("ask size", ticker.BestAskQuantity)
+	if err := fields.err(); err != nil {
+		return quote, err
+	}
 
 	quote = entities.CryptoQuote{
+		SymbolPair:  pair,
+		Market:      pair.Market,
+		TimeStamp:   time.UnixMilli(ticker.EventTime),
+		Rate:        rate,
+		OpenRate:    openRate,
+		HighRate:    highRate,
+		LowRate:     lowRate,
+		CloseRate:   closeRate,
+		Volume:      volume,
+		QuoteVolume: quoteVolume,
+		BidRate:     bidRate,
+		BidSize:     bidSize,
+		AskRate:     askRate,
+		AskSize:     askSize,
+	}
+
+	return quote, nil
+}
+
+func (binancePoller *BinancePoller) handleTrade(message []byte, pair entities.SymbolPair) {
+	var tradeMsg BinanceTradeMessage
+	err := json.Unmarshal(message, &tradeMsg)
+	if err != nil {
+		binancePoller.logger.Warn("Error unmarshaling Binance trade", logging.SymbolPair(pair), logging.Error(err))
+		binancePoller.feed.ParseError(pair)
+		return
+	}
+
+	trade, err := binancePoller.tradeToCryptoTrade(tradeMsg, binancePoller.symbolPairs())
+	if err != nil {
+		binancePoller.logger.Warn("Error converting Binance trade", logging.SymbolPair(pair), logging.Error(err))
+		binancePoller.feed.ParseError(pair)
+		return
+	}
+	if err := pair.CheckLotSize(trade.Quantity); err != nil {
+		binancePoller.logger.Warn("Binance trade quantity off the lot size", logging.SymbolPair(pair), logging.Error(err))
+	}
+
+	err = binancePoller.cryptoTradesWriter.Write([]entities.CryptoTrade{trade})
+	if err != nil {
+		binancePoller.logger.Error("Error writing Binance trade", logging.SymbolPair(pair), logging.Error(err))
+		binancePoller.feed.WriteError(pair)
+	}
+}
+
+func (binancePoller *BinancePoller) tradeToCryptoTrade(tradeMsg BinanceTradeMessage, pairs []entities.SymbolPair) (entities.CryptoTrade, error) {
+	var trade entities.CryptoTrade
+
+	pair, err := binancePoller.findSymbolPair(tradeMsg.Symbol, pairs)
+	if err != nil {
+		return trade, err
+	}
+
+	price, err := pair.ParsePrice(tradeMsg.Price)
+	if err != nil {
+		return trade, fmt.Errorf("invalid trade price: %w", err)
+	}
+	quantity, err := pair.ParseQuantity(tradeMsg.Quantity)
+	if err != nil {
+		return trade, fmt.Errorf("invalid trade quantity: %w", err)
+	}
+
+	tradeId := tradeMsg.TradeId
+	if tradeMsg.EventType == "aggTrade" {
+		tradeId = tradeMsg.AggregateTradeId
+	}
+
+	// The buyer being the maker means the taker sold.
+	side := entities.BuySide
+	if tradeMsg.IsBuyerMaker {
+		side = entities.SellSide
+	}
+
+	trade = entities.CryptoTrade{
 		SymbolPair: pair,
 		Market:     pair.Market,
-		TimeStamp:  time.UnixMilli(ticker.EventTime),
-		Rate:       rate,
-		OpenRate:   openRate,
-		HighRate:   highRate,
-		LowRate:    lowRate,
-		CloseRate:  closeRate,
-		Volume:     volume,
+		TradeId:    tradeId,
+		Price:      price,
+		Quantity:   quantity,
+		Side:       side,
+		TimeStamp:  time.UnixMilli(tradeMsg.TradeTime),
 	}
 
-	return quote, nil
+	return trade, nil
 }
 
 func (binancePoller *BinancePoller) findSymbolPair(symbol string, pairs []entities.SymbolPair) (entities.SymbolPair, error) {
@@ -0,0 +1,299 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// bitfinexConfChecksum makes Bitfinex send a "cs" message with the CRC32 of the top
+	// 25 levels after every book update.
+	bitfinexConfChecksum = 131072
+	// bitfinexBookLength is the number of levels per side of a subscribed book, which
+	// is also the number of levels the checksum covers.
+	bitfinexBookLength = 25
+)
+
+/*
+Book Snapshot:
+[
+
+	17082, //CHANNEL_ID
+	[
+	  [7254.7, 3, 3.3], //[PRICE, COUNT, AMOUNT], positive AMOUNT for bids
+	  [7255.6, 1, -0.5] //negative AMOUNT for asks
+	]
+
+]
+
+Updates carry a single level, a COUNT of 0 removes the level. Checksums arrive as
+[17082, "cs", -1563458479].
+*/
+
+type bitfinexOrderBook struct {
+	book   *pollers.OrderBook
+	synced bool
+}
+
+// bitfinexOrderBooks holds the books of one connection by their Bitfinex symbol. The
+// snapshot writer reads them while messages are applied.
+type bitfinexOrderBooks struct {
+	mu    sync.Mutex
+	books map[string]*bitfinexOrderBook
+}
+
+func newBitfinexOrderBooks(pairs []entities.SymbolPair) *bitfinexOrderBooks {
+	books := &bitfinexOrderBooks{books: make(map[string]*bitfinexOrderBook)}
+	books.add(pairs)
+	return books
+}
+
+// add starts out of sync books for the pairs, they sync with the snapshot of their
+// channel.
+func (books *bitfinexOrderBooks) add(pairs []entities.SymbolPair) {
+	if books == nil {
+		return
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	for _, pair := range pairs {
+		books.books[bitfinexSymbol(pair)] = &bitfinexOrderBook{book: pollers.NewOrderBook(pair)}
+	}
+}
+
+func (books *bitfinexOrderBooks) remove(pairs []entities.SymbolPair) {
+	if books == nil {
+		return
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	for _, pair := range pairs {
+		delete(books.books, bitfinexSymbol(pair))
+	}
+}
+
+// reset marks every book out of sync until its next snapshot arrives, on a new
+// connection or after resubscribing.
+func (books *bitfinexOrderBooks) reset() {
+	if books == nil {
+		return
+	}
+
+	books.mu.Lock()
+	defer books.mu.Unlock()
+	for _, book := range books.books {
+		book.synced = false
+		book.book.Clear()
+	}
+}
+
+func (books *bitfinexOrderBooks) snapshots(depth int, timeStamp time.Time) []entities.OrderBookSnapshot {
+	books.mu.Lock()
+	defer books.mu.Unlock()
+
+	var snapshots []entities.OrderBookSnapshot
+	for _, book := range books.books {
+		if book.synced {
+			snapshots = append(snapshots, book.book.Snapshot(depth, timeStamp))
+		}
+	}
+	return snapshots
+}
+
+// handleBook applies snapshots and updates of a book channel and verifies the
+// checksums. A book whose checksum does not match is resubscribed to get a new snapshot.
+func (bitfinexPoller *BitfinexPoller) handleBook(conn *websockets.Conn, chanId int, msg []interface{},
+	channel bitfinexChannel, channels *bitfinexChannels, books *bitfinexOrderBooks) error {
+	books.mu.Lock()
+	defer books.mu.Unlock()
+
+	book, found := books.books[bitfinexSymbol(channel.pair)]
+	if !found {
+		return nil
+	}
+
+	if msgType, ok := msg[1].(string); ok {
+		if msgType != "cs" || len(msg) < 3 || !book.synced {
+			return nil
+		}
+
+		checksum, _ := msg[2].(json.Number)
+		expected, err := checksum.Int64()
+		if err != nil {
+			bitfinexPoller.logger.Warn("Invalid Bitfinex checksum", logging.SymbolPair(channel.pair),
+				logging.ChanIdKey, chanId, "message", msg)
+			return nil
+		}
+		if bitfinexChecksum(book.book) == int32(expected) {
+			return nil
+		}
+
+		bitfinexPoller.logger.Warn("Checksum mismatch in Bitfinex order book, resubscribing",
+			logging.SymbolPair(channel.pair), logging.ChanIdKey, chanId)
+		book.synced = false
+		book.book.Clear()
+		return bitfinexPoller.resubscribeChannel(conn, chanId, channel, channels)
+	}
+
+	levels, ok := msg[1].([]interface{})
+	if !ok || len(levels) == 0 {
+		return nil
+	}
+
+	if _, isSnapshot := levels[0].([]interface{}); isSnapshot {
+		book.book.Clear()
+		for _, level := range levels {
+			levelData, _ := level.([]interface{})
+			if err := applyBitfinexLevel(book.book, levelData); err != nil {
+				bitfinexPoller.logger.Warn("Invalid Bitfinex book snapshot", logging.SymbolPair(channel.pair),
+					logging.ChanIdKey, chanId, logging.Error(err))
+				return nil
+			}
+		}
+		book.synced = true
+		return nil
+	}
+
+	if !book.synced {
+		return nil
+	}
+
+	if err := applyBitfinexLevel(book.book, levels); err != nil {
+		bitfinexPoller.logger.Warn("Invalid Bitfinex book update", logging.SymbolPair(channel.pair),
+			logging.ChanIdKey, chanId, logging.Error(err))
+		book.synced = false
+	}
+
+	return nil
+}
+
+func applyBitfinexLevel(book *pollers.OrderBook, level []interface{}) error {
+	if len(level) < 3 {
+		return fmt.Errorf("invalid level %v", level)
+	}
+
+	price, err := entities.ParseDecimal(bitfinexNumber(level[0]))
+	if err != nil {
+		return err
+	}
+	count, err := strconv.Atoi(bitfinexNumber(level[1]))
+	if err != nil {
+		return err
+	}
+	amount, err := entities.ParseDecimal(bitfinexNumber(level[2]))
+	if err != nil {
+		return err
+	}
+
+	side := book.Bids
+	if amount.Sign() < 0 {
+		side = book.Asks
+	}
+
+	if count == 0 {
+		side.Set(price, entities.Decimal{})
+		return nil
+	}
+	side.Set(price, amount.Abs())
+	return nil
+}
+
+// bitfinexChecksum computes the checksum Bitfinex sends for the book: the CRC32 of the
+// price and amount of the top bids and asks, interleaved and joined with ":".
+func bitfinexChecksum(book *pollers.OrderBook) int32 {
+	bids := book.Bids.Top(bitfinexBookLength)
+	asks := book.Asks.Top(bitfinexBookLength)
+
+	var parts []string
+	for i := 0; i < bitfinexBookLength; i++ {
+		if i < len(bids) {
+			parts = append(parts, bitfinexChecksumNumber(bids[i].Price), bitfinexChecksumNumber(bids[i].Quantity))
+		}
+		if i < len(asks) {
+			parts = append(parts, bitfinexChecksumNumber(asks[i].Price), bitfinexChecksumNumber(asks[i].Quantity.Neg()))
+		}
+	}
+
+	return int32(crc32.ChecksumIEEE([]byte(strings.Join(parts, ":"))))
+}
+
+// bitfinexChecksumNumber formats the value like JavaScript's Number.toString, which is
+// what Bitfinex computes the checksum over.
+func bitfinexChecksumNumber(value entities.Decimal) string {
+	value = value.Normalize()
+	if value.IsZero() {
+		return "0"
+	}
+
+	sign := ""
+	if value.Sign() < 0 {
+		sign = "-"
+	}
+
+	digits := strconv.FormatInt(value.Abs().Unscaled, 10)
+	length := len(digits)
+	// The value is 0.digits * 10^point.
+	point := length - int(value.Scale)
+
+	switch {
+	case length <= point && point <= 21:
+		return sign + digits + strings.Repeat("0", point-length)
+	case 0 < point && point <= 21:
+		return sign + digits[:point] + "." + digits[point:]
+	case -6 < point && point <= 0:
+		return sign + "0." + strings.Repeat("0", -point) + digits
+	}
+
+	exponent := point - 1
+	exponentSign := "+"
+	if exponent < 0 {
+		exponentSign = "-"
+		exponent = -exponent
+	}
+
+	mantissa := digits[:1]
+	if length > 1 {
+		mantissa += "." + digits[1:]
+	}
+	return sign + mantissa + "e" + exponentSign + strconv.Itoa(exponent)
+}
+
+func bitfinexSymbol(pair entities.SymbolPair) string {
+	return fmt.Sprintf("t%s%s",
+		strings.ToUpper(pair.BaseSymbol.Name),
+		strings.ToUpper(pair.QuoteSymbol.Name))
+}
+
+func bitfinexUnsubscribeMessage(chanId int) map[string]interface{} {
+	return map[string]interface{}{
+		"event":  "unsubscribe",
+		"chanId": chanId,
+	}
+}
+
+// bitfinexSubscribeMessage returns the subscribe event of the channel, books are
+// subscribed with the full precision and bitfinexBookLength levels.
+func bitfinexSubscribeMessage(channel string, symbol string) map[string]interface{} {
+	subMsg := map[string]interface{}{
+		"event":   "subscribe",
+		"channel": channel,
+		"symbol":  symbol,
+	}
+	if channel == "book" {
+		subMsg["prec"] = "P0"
+		subMsg["freq"] = "F0"
+		subMsg["len"] = strconv.Itoa(bitfinexBookLength)
+	}
+	return subMsg
+}
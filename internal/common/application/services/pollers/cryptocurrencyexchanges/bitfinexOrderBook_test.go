@@ -0,0 +1,86 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/entities"
+	"testing"
+)
+
+func TestBitfinexChecksum(t *testing.T) {
+	tests := []struct {
+		name string
+		bids [][2]string
+		asks [][2]string
+		want int32
+	}{
+		{name: "empty", want: 0},
+		{
+			// "7616.5:31.89055171:7617.5:-43.35811863"
+			name: "one level each",
+			bids: [][2]string{{"7616.5", "31.89055171"}},
+			asks: [][2]string{{"7617.5", "43.35811863"}},
+			want: -23421639,
+		},
+		{
+			// "100:1:101:-0.5:99:2.5", best levels first and asks negated.
+			name: "more bids than asks",
+			bids: [][2]string{{"99", "2.50"}, {"100", "1"}},
+			asks: [][2]string{{"101", "0.5"}},
+			want: 697662341,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			book := pollers.NewOrderBook(entities.SymbolPair{})
+			setBitfinexTestLevels(t, book.Bids, test.bids)
+			setBitfinexTestLevels(t, book.Asks, test.asks)
+
+			if got := bitfinexChecksum(book); got != test.want {
+				t.Errorf("bitfinexChecksum() = %d, want %d", got, test.want)
+			}
+		})
+	}
+}
+
+func TestBitfinexChecksumNumber(t *testing.T) {
+	tests := []struct {
+		value entities.Decimal
+		want  string
+	}{
+		{value: entities.Decimal{}, want: "0"},
+		{value: entities.Decimal{Unscaled: 0, Scale: 8}, want: "0"},
+		{value: entities.Decimal{Unscaled: 123, Scale: 0}, want: "123"},
+		{value: entities.Decimal{Unscaled: 150, Scale: 2}, want: "1.5"},
+		{value: entities.Decimal{Unscaled: -5, Scale: 1}, want: "-0.5"},
+		{value: entities.Decimal{Unscaled: 1, Scale: 6}, want: "0.000001"},
+		{value: entities.Decimal{Unscaled: 1, Scale: 7}, want: "1e-7"},
+		{value: entities.Decimal{Unscaled: -15, Scale: 8}, want: "-1.5e-7"},
+		{value: entities.Decimal{Unscaled: 1, Scale: -20}, want: "100000000000000000000"},
+		{value: entities.Decimal{Unscaled: 1, Scale: -21}, want: "1e+21"},
+		{value: entities.Decimal{Unscaled: 25, Scale: -21}, want: "2.5e+22"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.want, func(t *testing.T) {
+			if got := bitfinexChecksumNumber(test.value); got != test.want {
+				t.Errorf("bitfinexChecksumNumber(%#v) = %q, want %q", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func setBitfinexTestLevels(t *testing.T, side *pollers.OrderBookSide, levels [][2]string) {
+	t.Helper()
+	for _, level := range levels {
+		price, err := entities.ParseDecimal(level[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		quantity, err := entities.ParseDecimal(level[1])
+		if err != nil {
+			t.Fatal(err)
+		}
+		side.Set(price, quantity)
+	}
+}
@@ -2,22 +2,65 @@ package cryptocurrencyexchanges
 
 import (
 	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/consts"
 	"DataPoller/internal/common/domain/entities"
 	"DataPoller/internal/common/domain/repositories"
-	questrepositories "DataPoller/internal/common/infrastructure/repositories/quest"
+	"DataPoller/internal/common/infrastructure/health"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
+	"log/slog"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+)
 
-	"github.com/gorilla/websocket"
+// Bitfinex info codes announcing platform status changes.
+const (
+	bitfinexInfoRestart          = 20051
+	bitfinexInfoMaintenanceStart = 20060
+	bitfinexInfoMaintenanceEnd   = 20061
 )
 
+// bitfinexConfTimestamp makes Bitfinex append the MTS of the update to every channel
+// message, so quotes carry the exchange time instead of the time they were read.
+const bitfinexConfTimestamp = 32768
+
+// bitfinexMaxChannelsPerConnection is the number of channels Bitfinex lets one
+// connection subscribe, further subscriptions fail with error 10305.
+const bitfinexMaxChannelsPerConnection = 25
+
+// Bitfinex sends a heartbeat on every subscribed channel every 15 seconds, a connection
+// that missed three of them is dropped.
+var bitfinexConnectionOptions = websockets.Options{
+	Backoff:     websockets.DefaultBackoffPolicy,
+	ReadTimeout: 45 * time.Second,
+}
+
+// bitfinexMaintenanceTimeout is how long a connection waits for the end of a
+// maintenance. Bitfinex announces at most 120 seconds, a connection that missed the
+// 20061 resubscribes once they are over.
+var bitfinexMaintenanceTimeout = 120 * time.Second
+
 type BitfinexPoller struct {
 	dataSource         entities.DataSource
 	cryptoQuotesWriter repositories.CryptoQuotesWriter
+	cryptoTradesWriter repositories.CryptoTradesWriter
+	orderBookWriter    repositories.OrderBookWriter
+	feed               *metrics.Feed
+	logger             *slog.Logger
+	chunks             *pollers.LiveChunks
+	// maintenances counts the connections in a maintenance, see InMaintenance.
+	maintenances atomic.Int32
+	// pairsMu guards dataSource.SymbolPairs, which UpdateSymbolPairs replaces.
+	pairsMu sync.RWMutex
 }
 
 type BitfinexSubscribeMessage struct {
@@ -80,264 +123,728 @@ type BitfinexTickerData struct {
 	Low            string
 }
 
-func NewBitfinexPoller(dataSource entities.DataSource,
-	cryptoQuotesWriter repositories.CryptoQuotesWriter) pollers.QuotePoller {
-	return &BitfinexPoller{dataSource: dataSource, cryptoQuotesWriter: cryptoQuotesWriter}
+/*
+Trade Execution:
+[
+
+	17470, //CHANNEL_ID
+	"te", //MSG_TYPE
+	[
+	  401597395, //ID
+	  1574694478808, //MTS
+	  0.005, //AMOUNT
+	  7245.3 //PRICE
+	]
+
+]
+*/
+type BitfinexTradeData struct {
+	Id     string
+	Mts    string
+	Amount string
+	Price  string
+}
+
+func init() {
+	pollers.Register(consts.Bitfinex, NewBitfinexPoller)
 }
 
-func (bitfinexPoller *BitfinexPoller) Poll() {
-	symbolPairs := bitfinexPoller.dataSource.SymbolPairs
-	rateLimit := bitfinexPoller.dataSource.RateLimit
+func NewBitfinexPoller(dataSource entities.DataSource,
+	writers pollers.Writers) pollers.QuotePoller {
+	bitfinexPoller := &BitfinexPoller{
+		dataSource:         dataSource,
+		cryptoQuotesWriter: writers.CryptoQuotes,
+		cryptoTradesWriter: writers.CryptoTrades,
+		orderBookWriter:    writers.OrderBooks,
+		feed:               metrics.NewFeed(dataSource.Name),
+		logger:             slog.With(logging.DataSourceKey, dataSource.Name),
+	}
+	bitfinexPoller.chunks = pollers.NewLiveChunks(dataSource.SymbolPairs, bitfinexPoller.pairsPerConnection(),
+		bitfinexPoller.newChunk)
+	return bitfinexPoller
+}
 
-	if rateLimit == 0 {
-		go bitfinexPoller.pollSymbolChunk(symbolPairs)
-		select {}
+// pairsPerConnection returns how many pairs fit on one connection. The rate limit of
+// the data source counts channels, like the limit of Bitfinex, and every pair takes one
+// channel per streamed channel name.
+func (bitfinexPoller *BitfinexPoller) pairsPerConnection() int {
+	channels := bitfinexPoller.dataSource.RateLimit
+	if channels == 0 || channels > bitfinexMaxChannelsPerConnection {
+		channels = bitfinexMaxChannelsPerConnection
 	}
+	return max(channels/max(len(bitfinexPoller.channelNames()), 1), 1)
+}
+
+func (bitfinexPoller *BitfinexPoller) Poll(ctx context.Context) error {
+	return bitfinexPoller.chunks.Run(ctx)
+}
+
+// UpdateSymbolPairs subscribes the channels of added pairs and unsubscribes the
+// chanIds of removed pairs on the running connections.
+func (bitfinexPoller *BitfinexPoller) UpdateSymbolPairs(pairs []entities.SymbolPair) {
+	bitfinexPoller.pairsMu.Lock()
+	bitfinexPoller.dataSource.SymbolPairs = pairs
+	bitfinexPoller.pairsMu.Unlock()
+
+	bitfinexPoller.chunks.Update(pairs)
+}
+
+func (bitfinexPoller *BitfinexPoller) symbolPairs() []entities.SymbolPair {
+	bitfinexPoller.pairsMu.RLock()
+	defer bitfinexPoller.pairsMu.RUnlock()
+	return bitfinexPoller.dataSource.SymbolPairs
+}
+
+// bitfinexChannel is a subscribed channel, "ticker" or "trades", of one pair.
+type bitfinexChannel struct {
+	channel string
+	pair    entities.SymbolPair
+}
+
+// bitfinexChannels maps the chanIds of one connection to their channels. Bitfinex
+// assigns new chanIds on every connection, and the shutdown hook reads them while the
+// subscription stage may still be adding to them.
+type bitfinexChannels struct {
+	mu       sync.Mutex
+	channels map[int]bitfinexChannel
+	// changed is called with the number of channels whenever it changes.
+	changed func(count int)
+}
+
+func (channels *bitfinexChannels) reset() {
+	channels.mu.Lock()
+	defer channels.mu.Unlock()
+	channels.channels = make(map[int]bitfinexChannel)
+	channels.changed(0)
+}
+
+func (channels *bitfinexChannels) add(chanId int, channel string, pair entities.SymbolPair) {
+	channels.mu.Lock()
+	defer channels.mu.Unlock()
+	channels.channels[chanId] = bitfinexChannel{channel: channel, pair: pair}
+	channels.changed(len(channels.channels))
+}
+
+func (channels *bitfinexChannels) remove(chanId int) {
+	channels.mu.Lock()
+	defer channels.mu.Unlock()
+	delete(channels.channels, chanId)
+	channels.changed(len(channels.channels))
+}
 
-	totalPairs := len(symbolPairs)
-	for i := 0; i < totalPairs; i += rateLimit {
-		end := i + rateLimit
-		if end > totalPairs {
-			end = totalPairs
+func (channels *bitfinexChannels) get(chanId int) (bitfinexChannel, bool) {
+	channels.mu.Lock()
+	defer channels.mu.Unlock()
+	channel, ok := channels.channels[chanId]
+	return channel, ok
+}
+
+// pairIds returns the chanIds of the channels of the pair.
+func (channels *bitfinexChannels) pairIds(pair entities.SymbolPair) []int {
+	channels.mu.Lock()
+	defer channels.mu.Unlock()
+	var ids []int
+	for chanId, channel := range channels.channels {
+		if channel.pair.Id == pair.Id {
+			ids = append(ids, chanId)
 		}
+	}
+	return ids
+}
 
-		go bitfinexPoller.pollSymbolChunk(symbolPairs[i:end])
+func (channels *bitfinexChannels) ids() []int {
+	channels.mu.Lock()
+	defer channels.mu.Unlock()
+	ids := make([]int, 0, len(channels.channels))
+	for chanId := range channels.channels {
+		ids = append(ids, chanId)
+	}
+	return ids
+}
+
+// channelNames returns the Bitfinex channels the data source streams for every pair.
+func (bitfinexPoller *BitfinexPoller) channelNames() []string {
+	var names []string
+	if bitfinexPoller.dataSource.Streams(entities.TickerChannel) {
+		names = append(names, "ticker")
+	}
+	if bitfinexPoller.dataSource.Streams(entities.TradesChannel) {
+		names = append(names, "trades")
 	}
+	if bitfinexPoller.dataSource.Streams(entities.OrderBookChannel) {
+		names = append(names, "book")
+	}
+	return names
+}
 
-	select {}
+// bitfinexChunk is one connection and the pairs it streams.
+type bitfinexChunk struct {
+	name     string
+	logger   *slog.Logger
+	channels *bitfinexChannels
+	books    *bitfinexOrderBooks
+	// mu guards the pairs and the connection they were last subscribed on. Subscribe
+	// and live changes hold it, so a change is either sent on the current connection
+	// or picked up by the next Subscribe.
+	mu      sync.Mutex
+	pairs   []entities.SymbolPair
+	current *websockets.Conn
+	// maintenanceUntil is when the maintenance the current connection is in is given
+	// up on, zero outside of a maintenance.
+	maintenanceMu    sync.Mutex
+	maintenanceUntil time.Time
 }
 
-func (bitfinexPoller *BitfinexPoller) pollSymbolChunk(pairs []entities.SymbolPair) {
-	conn, _, err := websocket.DefaultDialer.Dial(bitfinexPoller.dataSource.ConnectionString, nil)
+// symbolPairs returns the pairs of the chunk.
+func (chunk *bitfinexChunk) symbolPairs() []entities.SymbolPair {
+	chunk.mu.Lock()
+	defer chunk.mu.Unlock()
+	return slices.Clone(chunk.pairs)
+}
 
-	if err != nil {
-		log.Fatal("Error connecting to BitfinexPoller WebSocket:", err)
-		return
+// inMaintenance reports whether the current connection of the chunk is in a maintenance.
+func (chunk *bitfinexChunk) inMaintenance() bool {
+	chunk.maintenanceMu.Lock()
+	defer chunk.maintenanceMu.Unlock()
+	return !chunk.maintenanceUntil.IsZero()
+}
+
+// maintenanceExpired reports whether the maintenance of the current connection should
+// have ended before now.
+func (chunk *bitfinexChunk) maintenanceExpired(now time.Time) bool {
+	chunk.maintenanceMu.Lock()
+	defer chunk.maintenanceMu.Unlock()
+	return !chunk.maintenanceUntil.IsZero() && now.After(chunk.maintenanceUntil)
+}
+
+func (bitfinexPoller *BitfinexPoller) newChunk(chunkId int, pairs []entities.SymbolPair) pollers.LiveChunk {
+	chunk := &bitfinexChunk{
+		name:  fmt.Sprintf("Bitfinex conn %d", chunkId),
+		pairs: pairs,
+	}
+	chunk.logger = bitfinexPoller.logger.With(logging.ConnectionIdKey, chunk.name)
+	chunk.channels = &bitfinexChannels{
+		changed: func(count int) {
+			bitfinexPoller.feed.SetSubscribedChannels(chunk.name, count)
+		},
+	}
+	if bitfinexPoller.dataSource.Streams(entities.OrderBookChannel) {
+		chunk.books = newBitfinexOrderBooks(pairs)
+	}
+
+	return pollers.LiveChunk{
+		Run: func(ctx context.Context) error {
+			return bitfinexPoller.pollSymbolChunk(ctx, chunk)
+		},
+		Add: func(pairs []entities.SymbolPair) {
+			bitfinexPoller.changeSymbolPairs(chunk, pairs, nil)
+		},
+		Remove: func(pairs []entities.SymbolPair) {
+			bitfinexPoller.changeSymbolPairs(chunk, nil, pairs)
+		},
+	}
+}
+
+func (bitfinexPoller *BitfinexPoller) pollSymbolChunk(ctx context.Context, chunk *bitfinexChunk) error {
+	if chunk.books != nil {
+		go pollers.WriteOrderBookSnapshots(ctx, bitfinexPoller.orderBookWriter, chunk.books.snapshots)
 	}
 
-	defer conn.Close()
+	conn := websockets.NewReconnectingConn(
+		chunk.name,
+		bitfinexPoller.dataSource.ConnectionString,
+		bitfinexConnectionOptions,
+		websockets.Handler{
+			Subscribe: func(ctx context.Context, conn *websockets.Conn) error {
+				chunk.mu.Lock()
+				defer chunk.mu.Unlock()
+
+				chunk.books.reset()
+				chunk.current = conn
+				// A new connection is operative until its info event says otherwise.
+				bitfinexPoller.endMaintenance(chunk, chunk.pairs)
+				return bitfinexPoller.subscribe(ctx, chunk, conn)
+			},
+			HandleMessage: func(conn *websockets.Conn, message []byte, receivedAt time.Time) error {
+				return bitfinexPoller.handleMessage(chunk, conn, message, receivedAt)
+			},
+			Unsubscribe: func(conn *websockets.Conn) {
+				for _, chanId := range chunk.channels.ids() {
+					if err := conn.WriteJSON(bitfinexUnsubscribeMessage(chanId)); err != nil {
+						chunk.logger.Warn("Error sending Bitfinex unsubscribe message", logging.Error(err))
+						break
+					}
+				}
 
-	log.Printf("Started BitfinexPoller conn for pairs: %+v\n", pairs)
+				chunk.mu.Lock()
+				defer chunk.mu.Unlock()
+				chunk.logger.Info("Closed Bitfinex channels", logging.SymbolPairs(chunk.pairs))
+			},
+		})
 
-	chanIdToPair := make(map[int]entities.SymbolPair)
+	return conn.Run(ctx)
+}
 
-	var params []string
+// changeSymbolPairs adds and removes pairs of the chunk. The channels of removed pairs
+// are unsubscribed by chanId, the channels of added pairs are subscribed and tied to
+// their chanIds once the "subscribed" events arrive. Failed writes are left to the
+// reconnect, which subscribes the changed pairs.
+func (bitfinexPoller *BitfinexPoller) changeSymbolPairs(chunk *bitfinexChunk, added []entities.SymbolPair,
+	removed []entities.SymbolPair) {
+	chunk.mu.Lock()
+	defer chunk.mu.Unlock()
 
-	for _, pair := range pairs {
-		symbolParam := fmt.Sprintf("t%s%s",
-			strings.ToUpper(pair.BaseSymbol.Name),
-			strings.ToUpper(pair.QuoteSymbol.Name))
-
-		subMsg := map[string]interface{}{
-			"event":   "subscribe",
-			"channel": "ticker",
-			"symbol":  symbolParam,
-		}
+	chunk.pairs = append(pollers.RemoveSymbolPairs(chunk.pairs, removed), added...)
+	chunk.books.add(added)
+	chunk.books.remove(removed)
 
-		msgJSON, err := json.Marshal(subMsg)
+	if chunk.current == nil {
+		return
+	}
 
-		if err != nil {
-			log.Printf("Error marshaling BitfinexPoller subscription JSON:", err)
-			continue
+	for _, pair := range removed {
+		for _, chanId := range chunk.channels.pairIds(pair) {
+			chunk.channels.remove(chanId)
+			if err := chunk.current.WriteJSON(bitfinexUnsubscribeMessage(chanId)); err != nil {
+				chunk.logger.Warn("Error sending Bitfinex unsubscribe message", logging.Error(err))
+				return
+			}
 		}
+		chunk.logger.Info("Unsubscribing Bitfinex channels", logging.SymbolPair(pair))
+	}
 
-		//log.Println(subMsg)
-		//log.Println(msgJSON)
-
-		if err = conn.WriteMessage(websocket.TextMessage, msgJSON); err != nil {
-			log.Printf("Error sending BitfinexPoller subscription message:", err)
-			continue
+	for _, pair := range added {
+		for _, channelName := range bitfinexPoller.channelNames() {
+			if err := chunk.current.WriteJSON(bitfinexSubscribeMessage(channelName, bitfinexSymbol(pair))); err != nil {
+				chunk.logger.Warn("Error sending Bitfinex subscribe message", logging.Error(err))
+				return
+			}
 		}
+		chunk.logger.Info("Subscribing Bitfinex channels", logging.SymbolPair(pair))
+	}
+}
 
-		subscribed := false
-		errorReceived := false
+// subscribe subscribes the channels of every pair one by one, waiting for each
+// confirmation so the chanId can be tied to its pair. The caller holds chunk.mu.
+func (bitfinexPoller *BitfinexPoller) subscribe(ctx context.Context, chunk *bitfinexChunk, conn *websockets.Conn) error {
+	logger, pairs, channels := chunk.logger, chunk.pairs, chunk.channels
+	logger.Info("Started Bitfinex connection", logging.SymbolPairs(pairs))
 
-		for !subscribed && !errorReceived {
-			_, msg, err := conn.ReadMessage()
-			if err != nil {
-				log.Printf("Error reading Bitfinex subscription response: %v", err)
-				break
+	channels.reset()
+
+	channelNames := bitfinexPoller.channelNames()
+	var params []string
+
+	flags := bitfinexConfTimestamp
+	if bitfinexPoller.dataSource.Streams(entities.OrderBookChannel) {
+		flags |= bitfinexConfChecksum
+	}
+	confMsg := map[string]interface{}{
+		"event": "conf",
+		"flags": flags,
+	}
+	if err := conn.WriteJSON(confMsg); err != nil {
+		return err
+	}
+
+	for _, pair := range pairs {
+		symbolParam := bitfinexSymbol(pair)
+
+		for _, channelName := range channelNames {
+			subMsg := bitfinexSubscribeMessage(channelName, symbolParam)
+
+			if err := conn.WriteJSON(subMsg); err != nil {
+				return err
 			}
 
-			var rawMsg map[string]interface{}
-			if err := json.Unmarshal(msg, &rawMsg); err == nil {
-				eventType, ok := rawMsg["event"].(string)
+			subscribed := false
+			errorReceived := false
 
-				if !ok {
-					log.Printf("Missing event field in", string(msg))
-					break
+			for !subscribed && !errorReceived {
+				_, msg, err := conn.ReadMessage()
+				if err != nil {
+					return fmt.Errorf("error reading Bitfinex subscription response: %w", err)
 				}
 
-				switch eventType {
-				case "info":
-					log.Println("Info message:", rawMsg)
-
-				case "error":
-					code := int(rawMsg["code"].(float64))
-					//msg := rawMsg["msg"].(string)
-					//log.Printf("Bitfinex subscription error [%d]: %s\n", code, msg)
-
-					switch code {
-					case 10300:
-						log.Println("Generic subscription failed for the pair", pair)
-					case 10301:
-						log.Println("Already subscribed to the pair:", pair)
-					case 10302:
-						log.Println("Unknown channel", subMsg["channel"].(string), "for pair", pair)
-					default:
-						log.Println("Unhandled error code", code, "for pair", pair)
-					}
+				var rawMsg map[string]interface{}
+				if err := json.Unmarshal(msg, &rawMsg); err == nil {
+					eventType, ok := rawMsg["event"].(string)
 
-					errorReceived = true
-				case "subscribed":
-					var subResp BitfinexSubscriptionResponse
-					if err := json.Unmarshal(msg, &subResp); err != nil {
-						log.Println("Failed to unmarshal subscribe response from Bitfinex:", err)
+					if !ok {
+						logger.Warn("Bitfinex event without event field", "message", string(msg))
 						break
 					}
-					chanIdToPair[subResp.ChannelId] = pair
-					log.Printf("Subscribed to %s, chanId: %d", subResp.Pair, subResp.ChannelId)
-					params = append(params, symbolParam)
-					subscribed = true
 
-				default:
-					log.Println("Unknown event type received from Bifinex:", eventType)
+					switch eventType {
+					case "info":
+						logger.Info("Bitfinex info event", "event", rawMsg)
+						bitfinexPoller.updatePlatformStatus(chunk, pairs, rawMsg, time.Now())
+
+					case "conf":
+						logger.Debug("Bitfinex configuration event", "event", rawMsg)
+
+					case "error":
+						code, _ := rawMsg["code"].(float64)
+						reason, _ := rawMsg["msg"].(string)
+						logger.Error("Bitfinex subscription failed", logging.SymbolPair(pair),
+							"channel", channelName, "code", int(code), "reason", bitfinexErrorReason(int(code), reason))
+
+						errorReceived = true
+					case "subscribed":
+						var subResp BitfinexSubscriptionResponse
+						if err := json.Unmarshal(msg, &subResp); err != nil {
+							logger.Warn("Error unmarshaling Bitfinex subscribe response", logging.Error(err))
+							break
+						}
+						channels.add(subResp.ChannelId, subResp.Channel, pair)
+						logger.Debug("Subscribed to Bitfinex channel", logging.SymbolPair(pair),
+							"channel", subResp.Channel, logging.ChanIdKey, subResp.ChannelId)
+						params = append(params, channelName+":"+symbolParam)
+						subscribed = true
+
+					default:
+						logger.Warn("Unknown Bitfinex event type", "event_type", eventType)
+					}
+				} else {
+					break
 				}
-			} else {
-				//log.Fatal("Received unexpected message: ", string(msg), "in subscription stage")
-				break
 			}
 		}
 	}
 
-	log.Println("Subscribed to Binance WebSocket streams:", params)
+	logger.Info("Subscribed to Bitfinex channels", "channels", params)
 
-	for {
-		_, message, err := conn.ReadMessage()
+	return nil
+}
 
-		if err != nil {
-			log.Println("Error reading Bitfinex message:", err)
-			return
+// decodeBitfinexMessage decodes channel messages, which are arrays, with json.Number
+// values. Events are objects and keep float64 values, which handleSystemEvent relies on.
+func decodeBitfinexMessage(message []byte) (interface{}, error) {
+	var rawMsg interface{}
+
+	trimmed := bytes.TrimSpace(message)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		err := json.Unmarshal(message, &rawMsg)
+		return rawMsg, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(trimmed))
+	decoder.UseNumber()
+	err := decoder.Decode(&rawMsg)
+	return rawMsg, err
+}
+
+func bitfinexNumber(value interface{}) string {
+	number, _ := value.(json.Number)
+	return number.String()
+}
+
+// InMaintenance reports whether a connection of the poller is in a maintenance
+// Bitfinex announced (20060) that has not ended yet (20061). Its tickers are expected
+// to be silent meanwhile.
+func (bitfinexPoller *BitfinexPoller) InMaintenance() bool {
+	return bitfinexPoller.maintenances.Load() > 0
+}
+
+// startMaintenance pauses the connection of the chunk until the maintenance ends or
+// bitfinexMaintenanceTimeout passed: channel updates are dropped, the books go out of
+// sync and the pairs do not go stale.
+func (bitfinexPoller *BitfinexPoller) startMaintenance(chunk *bitfinexChunk, pairs []entities.SymbolPair,
+	now time.Time) {
+	chunk.maintenanceMu.Lock()
+	defer chunk.maintenanceMu.Unlock()
+
+	if chunk.maintenanceUntil.IsZero() {
+		bitfinexPoller.maintenances.Add(1)
+		health.PauseExpectedQuotes(health.DefaultMonitor, bitfinexPoller.dataSource.Name, pairs)
+	}
+	chunk.maintenanceUntil = now.Add(bitfinexMaintenanceTimeout)
+	// Book updates are dropped as well, so the books are not written until the snapshots
+	// of the resubscribed channels arrive.
+	chunk.books.reset()
+}
+
+// endMaintenance resumes the connection of the chunk if it is in a maintenance.
+func (bitfinexPoller *BitfinexPoller) endMaintenance(chunk *bitfinexChunk, pairs []entities.SymbolPair) {
+	chunk.maintenanceMu.Lock()
+	defer chunk.maintenanceMu.Unlock()
+
+	if chunk.maintenanceUntil.IsZero() {
+		return
+	}
+	chunk.maintenanceUntil = time.Time{}
+	bitfinexPoller.maintenances.Add(-1)
+	health.ResumeExpectedQuotes(health.DefaultMonitor, bitfinexPoller.dataSource.Name, pairs)
+}
+
+func (bitfinexPoller *BitfinexPoller) handleMessage(chunk *bitfinexChunk, conn *websockets.Conn, message []byte,
+	receivedAt time.Time) error {
+	logger, channels, books := chunk.logger, chunk.channels, chunk.books
+
+	if chunk.maintenanceExpired(receivedAt) {
+		logger.Warn("Bitfinex maintenance did not end in time, resubscribing", "timeout", bitfinexMaintenanceTimeout)
+		bitfinexPoller.endMaintenance(chunk, chunk.symbolPairs())
+		if err := bitfinexPoller.resubscribe(conn, channels); err != nil {
+			return err
 		}
+	}
 
-		var rawMsg interface{}
-		if err := json.Unmarshal(message, &rawMsg); err != nil {
-			log.Println("Error unmarshaling Bitfinex message:", err)
-			continue
+	rawMsg, err := decodeBitfinexMessage(message)
+	if err != nil {
+		logger.Warn("Error unmarshaling Bitfinex message", logging.Error(err))
+		bitfinexPoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+		bitfinexPoller.feed.ParseError(entities.SymbolPair{})
+		return nil
+	}
+
+	switch msg := rawMsg.(type) {
+	case []interface{}:
+		if len(msg) < 2 {
+			logger.Warn("Invalid Bitfinex channel message", "message", string(message))
+			bitfinexPoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+			bitfinexPoller.feed.ParseError(entities.SymbolPair{})
+			return nil
 		}
 
-		log.Println(rawMsg)
+		if hbMsg, ok := msg[1].(string); ok && hbMsg == "hb" {
+			logger.Debug("Bitfinex heartbeat received", logging.ChanIdKey, msg[0])
+			bitfinexPoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+			return nil
+		}
 
-		switch msg := rawMsg.(type) {
-		case []interface{}:
-			if len(msg) != 2 {
-				log.Println("Invalid Bitfinex response")
-				continue
-			}
+		chanNumber, _ := msg[0].(json.Number)
+		chanId, err := chanNumber.Int64()
+		if err != nil {
+			logger.Warn("Invalid Bitfinex chanId", "message", string(message))
+			bitfinexPoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+			bitfinexPoller.feed.ParseError(entities.SymbolPair{})
+			return nil
+		}
+
+		channel, found := channels.get(int(chanId))
+		if !found {
+			logger.Debug("Update for unknown Bitfinex channel", logging.ChanIdKey, chanId)
+			bitfinexPoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+			return nil
+		}
+		bitfinexPoller.feed.MessageReceived(channel.pair, receivedAt)
+		if chunk.inMaintenance() {
+			// The channels are subscribed again once the maintenance ends.
+			return nil
+		}
 
-			if hbMsg, ok := msg[1].(string); ok && hbMsg == "hb" {
-				log.Println("Heartbeat received")
-				continue
+		msg, exchangeTime := bitfinexTimestamp(msg)
+		metrics.ObserveFeedLatency(bitfinexPoller.dataSource.Name, exchangeTime, receivedAt)
+
+		switch channel.channel {
+		case "ticker":
+			bitfinexPoller.handleTicker(msg, channel.pair, exchangeTime, receivedAt)
+		case "trades":
+			bitfinexPoller.handleTrades(msg, channel.pair)
+		case "book":
+			if books != nil {
+				return bitfinexPoller.handleBook(conn, int(chanId), msg, channel, channels, books)
 			}
+		}
 
-			chanId, ok := msg[0].(float64)
-			update, ok := msg[1].([]interface{})
+	case map[string]interface{}:
+		bitfinexPoller.feed.MessageReceived(entities.SymbolPair{}, receivedAt)
+		return bitfinexPoller.handleSystemEvent(chunk, conn, msg, receivedAt)
+	default:
+		logger.Warn("Unknown Bitfinex message", "message", string(message))
+	}
 
-			if !ok {
-				log.Println("Error in processing Bitfinex response", msg)
-				continue
-			}
-			if update == nil {
-				log.Printf("Channel %v returned null data", chanId)
-				continue
-			}
+	return nil
+}
 
-			//Sprintf or formatFloat?
-			tickerData := BitfinexTickerData{
-				Bid:            strconv.FormatFloat(update[0].(float64), 'f', -1, 64),
-				BidSize:        strconv.FormatFloat(update[1].(float64), 'f', -1, 64),
-				Ask:            strconv.FormatFloat(update[2].(float64), 'f', -1, 64),
-				AskSize:        strconv.FormatFloat(update[3].(float64), 'f', -1, 64),
-				DailyChange:    strconv.FormatFloat(update[4].(float64), 'f', -1, 64),
-				DailyChangeRel: strconv.FormatFloat(update[5].(float64), 'f', -1, 64),
-				LastPrice:      strconv.FormatFloat(update[6].(float64), 'f', -1, 64),
-				Volume:         strconv.FormatFloat(update[7].(float64), 'f', -1, 64),
-				High:           strconv.FormatFloat(update[8].(float64), 'f', -1, 64),
-				Low:            strconv.FormatFloat(update[9].(float64), 'f', -1, 64),
-			}
+// bitfinexTimestamp splits off the MTS that bitfinexConfTimestamp appends to channel
+// messages. The time is zero when the message carries none.
+func bitfinexTimestamp(msg []interface{}) ([]interface{}, time.Time) {
+	if len(msg) < 3 {
+		return msg, time.Time{}
+	}
+	// The checksum of [chanId, "cs", checksum] is a number as well.
+	if msgType, _ := msg[1].(string); msgType == "cs" && len(msg) < 4 {
+		return msg, time.Time{}
+	}
 
-			log.Println(tickerData)
-			log.Println(chanId)
-			log.Println(chanIdToPair[int(chanId)])
+	number, ok := msg[len(msg)-1].(json.Number)
+	if !ok {
+		return msg, time.Time{}
+	}
+	mts, err := number.Int64()
+	if err != nil {
+		return msg, time.Time{}
+	}
+	return msg[:len(msg)-1], time.UnixMilli(mts)
+}
 
-			quote, err := bitfinexPoller.tickerToCryptoQuote(tickerData, chanIdToPair[int(chanId)])
+func (bitfinexPoller *BitfinexPoller) handleTicker(msg []interface{}, pair entities.SymbolPair,
+	exchangeTime time.Time, receivedAt time.Time) {
+	update, ok := msg[1].([]interface{})
+	if !ok || len(update) < 10 {
+		bitfinexPoller.logger.Warn("Invalid Bitfinex ticker", logging.SymbolPair(pair), "message", msg)
+		bitfinexPoller.feed.ParseError(pair)
+		return
+	}
 
-			if err != nil {
-				log.Println("Error converting Binance ticker to quote:", err)
-				continue
-			}
+	// Numbers are kept in their JSON text form, so prices reach ParsePrice unchanged.
+	tickerData := BitfinexTickerData{
+		Bid:            bitfinexNumber(update[0]),
+		BidSize:        bitfinexNumber(update[1]),
+		Ask:            bitfinexNumber(update[2]),
+		AskSize:        bitfinexNumber(update[3]),
+		DailyChange:    bitfinexNumber(update[4]),
+		DailyChangeRel: bitfinexNumber(update[5]),
+		LastPrice:      bitfinexNumber(update[6]),
+		Volume:         bitfinexNumber(update[7]),
+		High:           bitfinexNumber(update[8]),
+		Low:            bitfinexNumber(update[9]),
+	}
 
-			err = bitfinexPoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
+	quote, err := bitfinexPoller.tickerToCryptoQuote(tickerData, pair)
 
-			if err != nil {
-				log.Println("Error writing Binance quote:", err)
-				continue
-			}
+	if err != nil {
+		bitfinexPoller.logger.Warn("Error converting Bitfinex ticker to quote", logging.SymbolPair(pair), logging.Error(err))
+		bitfinexPoller.feed.ParseError(pair)
+		return
+	}
 
-		case map[string]interface{}:
-			if event, ok := msg["event"].(string); ok {
-				switch event {
-				case "info":
-					log.Println("System info message:", msg)
-				case "subscribed":
-					log.Println("Subscribed confirmation:", msg)
-				case "error":
-					code := int(msg["code"].(float64))
-					//log.Printf("Bitfinex subscription error [%d]: %s\n", code, msg)
-
-					switch code {
-					case 10300:
-						log.Println("Generic subscription failed: ", msg)
-					case 10301:
-						log.Println("Already subscribed to the pair: ", msg)
-					case 10302:
-						log.Println("Unknown channel: ", msg["channel"].(string))
-					default:
-						log.Println("Unhandled error code: ", code)
-					}
-				default:
-					log.Println("Unhandled system event: ", event, msg)
-				}
-			} else {
-				log.Println("Unknown map message without 'event' field:", msg)
-			}
-		default:
-			log.Println("Unknown type", msg)
-		}
+	quote.ReceivedAt = receivedAt
+	quote.TimeStamp = receivedAt
+	if !exchangeTime.IsZero() {
+		quote.TimeStamp = exchangeTime
+	}
+
+	err = bitfinexPoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
+
+	if err != nil {
+		bitfinexPoller.logger.Error("Error writing Bitfinex quote", logging.SymbolPair(pair), logging.Error(err))
+		bitfinexPoller.feed.WriteError(pair)
+		return
+	}
+	bitfinexPoller.feed.QuoteWritten(pair)
+
+	bitfinexPoller.logger.Debug("Wrote Bitfinex quote", logging.SymbolPair(pair), "rate", quote.Rate)
+}
+
+// handleTrades writes "te" updates, which Bitfinex sends as soon as a trade executes.
+// The "tu" update that follows repeats the trade and is skipped, like the snapshot of
+// recent trades sent on subscribe, which would duplicate trades after a reconnect.
+func (bitfinexPoller *BitfinexPoller) handleTrades(msg []interface{}, pair entities.SymbolPair) {
+	if len(msg) != 3 {
+		return
+	}
+
+	updateType, _ := msg[1].(string)
+	if updateType != "te" {
+		return
+	}
+
+	update, ok := msg[2].([]interface{})
+	if !ok || len(update) < 4 {
+		bitfinexPoller.logger.Warn("Invalid Bitfinex trade", logging.SymbolPair(pair), "message", msg)
+		bitfinexPoller.feed.ParseError(pair)
+		return
+	}
+
+	trade, err := bitfinexPoller.tradeToCryptoTrade(BitfinexTradeData{
+		Id:     bitfinexNumber(update[0]),
+		Mts:    bitfinexNumber(update[1]),
+		Amount: bitfinexNumber(update[2]),
+		Price:  bitfinexNumber(update[3]),
+	}, pair)
+	if err != nil {
+		bitfinexPoller.logger.Warn("Error converting Bitfinex trade", logging.SymbolPair(pair), logging.Error(err))
+		bitfinexPoller.feed.ParseError(pair)
+		return
+	}
+	if err := pair.CheckLotSize(trade.Quantity); err != nil {
+		bitfinexPoller.logger.Warn("Bitfinex trade quantity off the lot size", logging.SymbolPair(pair), logging.Error(err))
+	}
+
+	err = bitfinexPoller.cryptoTradesWriter.Write([]entities.CryptoTrade{trade})
+	if err != nil {
+		bitfinexPoller.logger.Error("Error writing Bitfinex trade", logging.SymbolPair(pair), logging.Error(err))
+		bitfinexPoller.feed.WriteError(pair)
+	}
+}
+
+func (bitfinexPoller *BitfinexPoller) tradeToCryptoTrade(tradeData BitfinexTradeData, pair entities.SymbolPair) (entities.CryptoTrade, error) {
+	var trade entities.CryptoTrade
 
+	tradeId, err := strconv.ParseInt(tradeData.Id, 10, 64)
+	if err != nil {
+		return trade, fmt.Errorf("invalid trade id: %w", err)
+	}
+	mts, err := strconv.ParseInt(tradeData.Mts, 10, 64)
+	if err != nil {
+		return trade, fmt.Errorf("invalid trade time: %w", err)
+	}
+	price, err := pair.ParsePrice(tradeData.Price)
+	if err != nil {
+		return trade, fmt.Errorf("invalid trade price: %w", err)
+	}
+	// The amount is negative when the taker sold.
+	amount, err := pair.ParseQuantity(tradeData.Amount)
+	if err != nil {
+		return trade, fmt.Errorf("invalid trade amount: %w", err)
+	}
+
+	side := entities.BuySide
+	if amount.Sign() < 0 {
+		side = entities.SellSide
+	}
+
+	trade = entities.CryptoTrade{
+		SymbolPair: pair,
+		Market:     pair.Market,
+		TradeId:    tradeId,
+		Price:      price,
+		Quantity:   amount.Abs(),
+		Side:       side,
+		TimeStamp:  time.UnixMilli(mts),
 	}
+
+	return trade, nil
 }
 
 func (bitfinexPoller *BitfinexPoller) tickerToCryptoQuote(ticker BitfinexTickerData, pair entities.SymbolPair) (entities.CryptoQuote, error) {
 	var quote entities.CryptoQuote
 
-	rate, _ := questrepositories.ToDatabaseRate(ticker.LastPrice)
-	openRate, _ := questrepositories.ToDatabaseRate(ticker.Bid)
-	highRate, _ := questrepositories.ToDatabaseRate(ticker.High)
-	lowRate, _ := questrepositories.ToDatabaseRate(ticker.Low)
+	rate, err := pair.ParsePrice(ticker.LastPrice)
+	if err != nil {
+		return quote, fmt.Errorf("invalid last price: %w", err)
+	}
+
+	// Bitfinex has no open price in the ticker, so it is derived from the daily change.
+	fields := tickerFields{pair: pair}
+	openRate := fields.openRate(rate, ticker.DailyChange)
+	highRate := fields.price("high price", ticker.High)
+	lowRate := fields.price("low price", ticker.Low)
 	closeRate := rate
-	volume, _ := questrepositories.ToDatabaseRate(ticker.Volume)
+	volume := fields.quantity("volume", ticker.Volume)
+	bidRate := fields.price("bid price", ticker.Bid)
+	bidSize := fields.quantity("bid size", ticker.BidSize)
+	askRate := fields.price("ask price", ticker.Ask)
+	askSize := fields.quantity("ask size", ticker.AskSize)
+	if err := fields.err(); err != nil {
+		return quote, err
+	}
 
 	quote = entities.CryptoQuote{
 		SymbolPair: pair,
 		Market:     pair.Market,
-		TimeStamp:  time.Now(),
 		Rate:       rate,
 		OpenRate:   openRate,
 		HighRate:   highRate,
 		LowRate:    lowRate,
 		CloseRate:  closeRate,
 		Volume:     volume,
+		BidRate:    bidRate,
+		BidSize:    bidSize,
+		AskRate:    askRate,
+		AskSize:    askSize,
 	}
 
 	return quote, nil
@@ -354,17 +861,15 @@ func (bitfinexPoller *BitfinexPoller) findSymbolPair(symbol string, pairs []enti
 	return entities.SymbolPair{}, fmt.Errorf("symbol pair not found for %s", symbol)
 }
 
-func handleSystemEvent(RawMsg []byte) {
-	var msg map[string]interface{}
-	if err := json.Unmarshal(RawMsg, &msg); err != nil {
-		log.Printf("Error unmarshaling system message: %v", err)
-		return
-	}
-
+// handleSystemEvent handles the event messages that arrive after the subscription
+// stage. Returning websockets.ErrReconnect makes the connection start over.
+func (bitfinexPoller *BitfinexPoller) handleSystemEvent(chunk *bitfinexChunk, conn *websockets.Conn,
+	msg map[string]interface{}, receivedAt time.Time) error {
+	logger, channels := chunk.logger, chunk.channels
 	event, ok := msg["event"].(string)
 	if !ok {
-		log.Println("Received system message without 'event':", msg)
-		return
+		logger.Warn("Bitfinex event without event field", "event", msg)
+		return nil
 	}
 
 	switch event {
@@ -372,46 +877,130 @@ func handleSystemEvent(RawMsg []byte) {
 		code, hasCode := msg["code"].(float64)
 		if hasCode {
 			switch int(code) {
-			case 20051:
-				log.Println("Info: Stop, Restart WebSocket Server. Reconnecting")
-			case 20060:
-				log.Println("Info: Entering maintenance mode. Pause activity for at most 120 seconds.")
-				//waiting for 20061 -
-			case 20061:
-				log.Println("Info: Maintenance ended. Resubscribe to channels needed.")
+			case bitfinexInfoRestart:
+				logger.Warn("Bitfinex websocket server restarts, reconnecting")
+				return websockets.ErrReconnect
+			case bitfinexInfoMaintenanceStart:
+				logger.Warn("Bitfinex entered maintenance, pausing", "timeout", bitfinexMaintenanceTimeout)
+				bitfinexPoller.startMaintenance(chunk, chunk.symbolPairs(), receivedAt)
+			case bitfinexInfoMaintenanceEnd:
+				logger.Info("Bitfinex maintenance ended, resubscribing")
+				bitfinexPoller.endMaintenance(chunk, chunk.symbolPairs())
+				return bitfinexPoller.resubscribe(conn, channels)
 			default:
-				log.Printf("Info message with unhandled code (%d): %v\n", int(code), msg)
+				logger.Info("Bitfinex info event with unhandled code", "code", int(code), "event", msg)
 			}
 		} else {
-			log.Println("Info message without code:", msg)
+			logger.Info("Bitfinex info event", "event", msg)
+			bitfinexPoller.updatePlatformStatus(chunk, chunk.symbolPairs(), msg, receivedAt)
 		}
 
 	case "subscribed":
-		log.Printf("Sucessfully subscribed to channel: %v", msg)
+		var subResp BitfinexSubscriptionResponse
+		if err := remarshal(msg, &subResp); err != nil {
+			logger.Warn("Error unmarshaling Bitfinex subscribe response", logging.Error(err))
+			return nil
+		}
 
-	case "error":
-		code := int(msg["code"].(float64))
-		msgText := msg["msg"].(string)
-		log.Printf("Error (%d): %s", code, msgText)
-
-		switch code {
-		case 10000:
-			log.Println("Unknown event")
-		case 10001:
-			log.Println("Unknown pair")
-		case 10300:
-			log.Println("Generic subscription failed")
-		case 10301:
-			log.Println("Already subscribed")
-		case 10302:
-			log.Println("Unknown channel")
-		case 10305:
-			log.Println("Reached limit of open channels")
-		default:
-			log.Println("Unhandled error code")
+		// The pair of a "tTESTBTC:TESTUSD" symbol comes back as "TESTBTC:TESTUSD".
+		pair, err := bitfinexPoller.findSymbolPair(strings.ReplaceAll(subResp.Pair, ":", ""), bitfinexPoller.symbolPairs())
+		if err != nil {
+			logger.Warn("Subscribed to unexpected Bitfinex pair", logging.Error(err))
+			return nil
 		}
+		channels.add(subResp.ChannelId, subResp.Channel, pair)
+		logger.Info("Subscribed to Bitfinex channel", logging.SymbolPair(pair),
+			"channel", subResp.Channel, logging.ChanIdKey, subResp.ChannelId)
+
+	case "unsubscribed":
+		logger.Debug("Unsubscribed from Bitfinex channel", logging.ChanIdKey, msg["chanId"])
+
+	case "conf":
+		logger.Debug("Bitfinex configuration event", "event", msg)
+
+	case "error":
+		code, _ := msg["code"].(float64)
+		reason, _ := msg["msg"].(string)
+		logger.Error("Bitfinex error event", "code", int(code), "reason", bitfinexErrorReason(int(code), reason))
 
 	default:
-		log.Println("Unhandled system event type:", event, "with message: ", msg)
+		logger.Warn("Unhandled Bitfinex event type", "event_type", event, "event", msg)
+	}
+
+	return nil
+}
+
+// bitfinexErrorReason describes the known error codes, falling back to the message of
+// the event.
+func bitfinexErrorReason(code int, reason string) string {
+	switch code {
+	case 10000:
+		return "unknown event"
+	case 10001:
+		return "unknown pair"
+	case 10300:
+		return "generic subscription failure"
+	case 10301:
+		return "already subscribed"
+	case 10302:
+		return "unknown channel"
+	case 10305:
+		return "reached limit of open channels"
+	}
+	return reason
+}
+
+// updatePlatformStatus applies the platform status of the info event Bitfinex sends on
+// connect, 1 while operative and 0 during maintenance, to the connection of the chunk.
+func (bitfinexPoller *BitfinexPoller) updatePlatformStatus(chunk *bitfinexChunk, pairs []entities.SymbolPair,
+	msg map[string]interface{}, now time.Time) {
+	platform, ok := msg["platform"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	status, ok := platform["status"].(float64)
+	if !ok {
+		return
+	}
+	if status == 0 {
+		bitfinexPoller.startMaintenance(chunk, pairs, now)
+	} else {
+		bitfinexPoller.endMaintenance(chunk, pairs)
+	}
+}
+
+// resubscribe unsubscribes every chanId of the connection and subscribes its channel
+// again. The new chanIds are registered when the "subscribed" events arrive.
+func (bitfinexPoller *BitfinexPoller) resubscribe(conn *websockets.Conn, channels *bitfinexChannels) error {
+	for _, chanId := range channels.ids() {
+		channel, found := channels.get(chanId)
+		if !found {
+			continue
+		}
+		if err := bitfinexPoller.resubscribeChannel(conn, chanId, channel, channels); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (bitfinexPoller *BitfinexPoller) resubscribeChannel(conn *websockets.Conn, chanId int,
+	channel bitfinexChannel, channels *bitfinexChannels) error {
+	channels.remove(chanId)
+
+	if err := conn.WriteJSON(bitfinexUnsubscribeMessage(chanId)); err != nil {
+		return err
+	}
+
+	return conn.WriteJSON(bitfinexSubscribeMessage(channel.channel, bitfinexSymbol(channel.pair)))
+}
+
+// remarshal converts a decoded event into its typed form.
+func remarshal(msg map[string]interface{}, v interface{}) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
 	}
+	return json.Unmarshal(data, v)
 }
@@ -0,0 +1,417 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/health"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// bitfinexTestTimeout bounds every wait of the tests. A reconnect waits about a second
+// for the backoff of the connection.
+const bitfinexTestTimeout = 5 * time.Second
+
+// fakeBitfinex is a websocket server answering conf, subscribe and unsubscribe events
+// like Bitfinex. Every subscription gets a chanId unique across connections.
+type fakeBitfinex struct {
+	t        *testing.T
+	server   *httptest.Server
+	conns    chan *fakeBitfinexConn
+	chanIds  atomic.Int64
+	upgrader websocket.Upgrader
+}
+
+// fakeBitfinexConn is one client connection of the fake server. events receives every
+// event the client sent, after the server answered it.
+type fakeBitfinexConn struct {
+	t       *testing.T
+	conn    *websocket.Conn
+	writeMu sync.Mutex
+	events  chan map[string]interface{}
+	// chanIds maps the channel names to their chanId on this connection.
+	mu      sync.Mutex
+	chanIds map[string]int
+}
+
+func newFakeBitfinex(t *testing.T) *fakeBitfinex {
+	fake := &fakeBitfinex{t: t, conns: make(chan *fakeBitfinexConn, 10)}
+	fake.server = httptest.NewServer(http.HandlerFunc(fake.serve))
+	t.Cleanup(fake.server.Close)
+	return fake
+}
+
+func (fake *fakeBitfinex) url() string {
+	return "ws" + strings.TrimPrefix(fake.server.URL, "http")
+}
+
+func (fake *fakeBitfinex) serve(w http.ResponseWriter, r *http.Request) {
+	ws, err := fake.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		fake.t.Errorf("upgrade failed: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	conn := &fakeBitfinexConn{
+		t:       fake.t,
+		conn:    ws,
+		events:  make(chan map[string]interface{}, 100),
+		chanIds: make(map[string]int),
+	}
+	conn.send(map[string]interface{}{"event": "info", "version": 2, "platform": map[string]interface{}{"status": 1}})
+	fake.conns <- conn
+
+	for {
+		var event map[string]interface{}
+		if err := ws.ReadJSON(&event); err != nil {
+			return
+		}
+
+		switch event["event"] {
+		case "conf":
+			conn.send(map[string]interface{}{"event": "conf", "status": "OK"})
+		case "subscribe":
+			channel, _ := event["channel"].(string)
+			symbol, _ := event["symbol"].(string)
+			chanId := int(fake.chanIds.Add(1))
+			conn.mu.Lock()
+			conn.chanIds[channel] = chanId
+			conn.mu.Unlock()
+			conn.send(map[string]interface{}{"event": "subscribed", "channel": channel, "chanId": chanId,
+				"symbol": symbol, "pair": strings.TrimPrefix(symbol, "t")})
+		case "unsubscribe":
+			conn.send(map[string]interface{}{"event": "unsubscribed", "status": "OK", "chanId": event["chanId"]})
+		}
+		conn.events <- event
+	}
+}
+
+// accept waits for the next client connection.
+func (fake *fakeBitfinex) accept() *fakeBitfinexConn {
+	fake.t.Helper()
+	select {
+	case conn := <-fake.conns:
+		return conn
+	case <-time.After(bitfinexTestTimeout):
+		fake.t.Fatal("timed out waiting for a connection")
+		return nil
+	}
+}
+
+// send writes a message to the client. Write errors are ignored: the poller closes its
+// connections without waiting for the answers to its last unsubscribe events, and a
+// message that does not arrive fails the test waiting for it anyway.
+func (conn *fakeBitfinexConn) send(message interface{}) {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+	_ = conn.conn.WriteJSON(message)
+}
+
+func (conn *fakeBitfinexConn) chanId(channel string) int {
+	conn.mu.Lock()
+	defer conn.mu.Unlock()
+	return conn.chanIds[channel]
+}
+
+// expectEvents waits for the client to send the events, in any order, and fails on any
+// other event in between. Events are described as "subscribe:ticker" or "unsubscribe:42".
+func (conn *fakeBitfinexConn) expectEvents(expected ...string) {
+	conn.t.Helper()
+
+	missing := make(map[string]int)
+	for _, event := range expected {
+		missing[event]++
+	}
+
+	for len(missing) > 0 {
+		select {
+		case event := <-conn.events:
+			description := describeBitfinexEvent(event)
+			if missing[description] == 0 {
+				conn.t.Fatalf("unexpected event %s, still waiting for %v", description, missing)
+			}
+			missing[description]--
+			if missing[description] == 0 {
+				delete(missing, description)
+			}
+		case <-time.After(bitfinexTestTimeout):
+			conn.t.Fatalf("timed out waiting for events %v", missing)
+		}
+	}
+}
+
+func describeBitfinexEvent(event map[string]interface{}) string {
+	switch event["event"] {
+	case "subscribe":
+		return "subscribe:" + event["channel"].(string)
+	case "unsubscribe":
+		return fmt.Sprintf("unsubscribe:%v", event["chanId"])
+	}
+	return event["event"].(string)
+}
+
+// sendTicker sends a ticker update of the last price with the MTS the conf flag adds.
+func (conn *fakeBitfinexConn) sendTicker(lastPrice float64) {
+	conn.send([]interface{}{conn.chanId("ticker"),
+		[]float64{99, 1, 101, 1, 0, 0, lastPrice, 1000, 110, 90},
+		time.Now().UnixMilli()})
+}
+
+func (conn *fakeBitfinexConn) sendHeartbeat() {
+	conn.send([]interface{}{conn.chanId("ticker"), "hb"})
+}
+
+func (conn *fakeBitfinexConn) sendInfo(code int) {
+	conn.send(map[string]interface{}{"event": "info", "code": code})
+}
+
+// fakeQuotesWriter hands the written quotes to the test.
+type fakeQuotesWriter struct {
+	quotes chan entities.CryptoQuote
+}
+
+func (writer *fakeQuotesWriter) Write(quotes []entities.CryptoQuote) error {
+	for _, quote := range quotes {
+		writer.quotes <- quote
+	}
+	return nil
+}
+
+func (writer *fakeQuotesWriter) Close() error {
+	return nil
+}
+
+// expectQuote waits for the next quote and checks its rate.
+func (writer *fakeQuotesWriter) expectQuote(t *testing.T, rate string) {
+	t.Helper()
+	select {
+	case quote := <-writer.quotes:
+		if quote.Rate.String() != rate {
+			t.Fatalf("got a quote with rate %s, want %s", quote.Rate, rate)
+		}
+	case <-time.After(bitfinexTestTimeout):
+		t.Fatalf("timed out waiting for the quote with rate %s", rate)
+	}
+}
+
+// startBitfinexPoller polls a fake server for tickers and trades of the bases against
+// USD, BTC when none are given, until the test ends. Every pair gets a connection of
+// its own. The quotes are tracked by the health monitor like in the factory.
+func startBitfinexPoller(t *testing.T, name string, bases ...string) (*BitfinexPoller, *fakeBitfinex, *fakeQuotesWriter) {
+	fake := newFakeBitfinex(t)
+	quotes := &fakeQuotesWriter{quotes: make(chan entities.CryptoQuote, 100)}
+
+	if len(bases) == 0 {
+		bases = []string{"BTC"}
+	}
+	var pairs []entities.SymbolPair
+	for i, base := range bases {
+		pairs = append(pairs, entities.SymbolPair{
+			Id:                i + 1,
+			BaseSymbol:        entities.Symbol{Id: i + 2, Name: base},
+			QuoteSymbol:       entities.Symbol{Id: 1, Name: "USD"},
+			PricePrecision:    2,
+			QuantityPrecision: 8,
+		})
+	}
+	dataSource := entities.DataSource{
+		Name:             name,
+		ConnectionString: fake.url(),
+		RateLimit:        2,
+		SymbolPairs:      pairs,
+		Channels:         []string{entities.TickerChannel, entities.TradesChannel},
+	}
+
+	poller := NewBitfinexPoller(dataSource, pollers.Writers{
+		CryptoQuotes: health.TrackQuotes(health.DefaultMonitor, dataSource, quotes),
+	}).(*BitfinexPoller)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := poller.Poll(ctx); err != nil && !errors.Is(err, context.Canceled) {
+			t.Errorf("Poll failed: %v", err)
+		}
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return poller, fake, quotes
+}
+
+// subscribed accepts the next connection and waits until both channels are subscribed.
+func (fake *fakeBitfinex) subscribed() *fakeBitfinexConn {
+	fake.t.Helper()
+	conn := fake.accept()
+	conn.expectEvents("conf", "subscribe:ticker", "subscribe:trades")
+	return conn
+}
+
+// resubscribed sends the end of a maintenance and waits until every chanId of the
+// connection is unsubscribed and its channel subscribed again.
+func (conn *fakeBitfinexConn) resubscribed() {
+	conn.t.Helper()
+	tickerId, tradesId := conn.chanId("ticker"), conn.chanId("trades")
+
+	conn.sendInfo(bitfinexInfoMaintenanceEnd)
+	conn.expectEvents(fmt.Sprintf("unsubscribe:%d", tickerId), fmt.Sprintf("unsubscribe:%d", tradesId),
+		"subscribe:ticker", "subscribe:trades")
+
+	if conn.chanId("ticker") == tickerId || conn.chanId("trades") == tradesId {
+		conn.t.Fatalf("channels kept their chanIds %d and %d after resubscribing", tickerId, tradesId)
+	}
+}
+
+// stalePairs returns the pairs of the data source the monitor reports as stale when
+// every quote is stale right away.
+func stalePairs(dataSource string) []string {
+	var pairs []string
+	for _, stale := range health.DefaultMonitor.Report(0).StalePairs {
+		if stale.DataSource == dataSource {
+			pairs = append(pairs, stale.SymbolPair)
+		}
+	}
+	return pairs
+}
+
+func waitForMaintenance(t *testing.T, poller *BitfinexPoller, maintenance bool) {
+	t.Helper()
+	deadline := time.Now().Add(bitfinexTestTimeout)
+	for poller.InMaintenance() != maintenance {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for InMaintenance to be %v", maintenance)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBitfinexPollerReconnectsOnRestart(t *testing.T) {
+	_, fake, quotes := startBitfinexPoller(t, "bitfinex-restart")
+
+	first := fake.subscribed()
+	first.sendTicker(100)
+	quotes.expectQuote(t, "100.00")
+
+	first.sendInfo(bitfinexInfoRestart)
+
+	second := fake.subscribed()
+	second.sendTicker(101)
+	quotes.expectQuote(t, "101.00")
+}
+
+func TestBitfinexPollerPausesDuringMaintenance(t *testing.T) {
+	const dataSource = "bitfinex-maintenance"
+	poller, fake, quotes := startBitfinexPoller(t, dataSource)
+
+	conn := fake.subscribed()
+	conn.sendTicker(100)
+	quotes.expectQuote(t, "100.00")
+	if stale := stalePairs(dataSource); len(stale) != 1 {
+		t.Fatalf("got stale pairs %v before the maintenance, want BTCUSD", stale)
+	}
+
+	conn.sendInfo(bitfinexInfoMaintenanceStart)
+	waitForMaintenance(t, poller, true)
+
+	if stale := stalePairs(dataSource); len(stale) != 0 {
+		t.Fatalf("got stale pairs %v during the maintenance, want none", stale)
+	}
+
+	// The update sent during the maintenance is dropped, the first quote written is the
+	// one sent after it.
+	conn.sendTicker(102)
+	conn.resubscribed()
+	waitForMaintenance(t, poller, false)
+
+	conn.sendTicker(103)
+	quotes.expectQuote(t, "103.00")
+
+	if stale := stalePairs(dataSource); len(stale) != 1 {
+		t.Fatalf("got stale pairs %v after the maintenance, want BTCUSD", stale)
+	}
+}
+
+func TestBitfinexPollerResubscribesAfterMaintenance(t *testing.T) {
+	_, fake, quotes := startBitfinexPoller(t, "bitfinex-resubscribe")
+
+	conn := fake.subscribed()
+	conn.sendInfo(bitfinexInfoMaintenanceStart)
+	conn.resubscribed()
+
+	// The ticker arrives on its new chanId.
+	conn.sendTicker(104)
+	quotes.expectQuote(t, "104.00")
+}
+
+func TestBitfinexPollerGivesUpOnMaintenanceWithoutEnd(t *testing.T) {
+	timeout := bitfinexMaintenanceTimeout
+	bitfinexMaintenanceTimeout = 100 * time.Millisecond
+	t.Cleanup(func() { bitfinexMaintenanceTimeout = timeout })
+
+	const dataSource = "bitfinex-maintenance-timeout"
+	poller, fake, quotes := startBitfinexPoller(t, dataSource)
+
+	conn := fake.subscribed()
+	conn.sendInfo(bitfinexInfoMaintenanceStart)
+	waitForMaintenance(t, poller, true)
+
+	// The 20061 never arrives, the first message after the timeout resubscribes.
+	time.Sleep(2 * bitfinexMaintenanceTimeout)
+	tickerId, tradesId := conn.chanId("ticker"), conn.chanId("trades")
+	conn.sendHeartbeat()
+	conn.expectEvents(fmt.Sprintf("unsubscribe:%d", tickerId), fmt.Sprintf("unsubscribe:%d", tradesId),
+		"subscribe:ticker", "subscribe:trades")
+	waitForMaintenance(t, poller, false)
+
+	if stale := stalePairs(dataSource); len(stale) != 1 {
+		t.Fatalf("got stale pairs %v after the maintenance timed out, want BTCUSD", stale)
+	}
+	conn.sendTicker(105)
+	quotes.expectQuote(t, "105.00")
+}
+
+func TestBitfinexPollerTracksMaintenancePerConnection(t *testing.T) {
+	const dataSource = "bitfinex-maintenance-connections"
+	poller, fake, quotes := startBitfinexPoller(t, dataSource, "BTC", "ETH")
+
+	first, second := fake.subscribed(), fake.subscribed()
+	first.sendInfo(bitfinexInfoMaintenanceStart)
+	second.sendInfo(bitfinexInfoMaintenanceStart)
+	waitForMaintenance(t, poller, true)
+
+	// Only the first connection sees the end of the maintenance.
+	first.resubscribed()
+	second.sendTicker(106)
+	first.sendTicker(107)
+	quotes.expectQuote(t, "107.00")
+
+	if !poller.InMaintenance() {
+		t.Fatal("the second connection left the maintenance with the first")
+	}
+	if stale := stalePairs(dataSource); len(stale) != 1 {
+		t.Fatalf("got stale pairs %v, want the pair of the first connection only", stale)
+	}
+}
+
+func TestBitfinexPollerSurvivesErrorWithoutCode(t *testing.T) {
+	_, fake, quotes := startBitfinexPoller(t, "bitfinex-error")
+
+	conn := fake.subscribed()
+	conn.send(map[string]interface{}{"event": "error", "msg": "unknown"})
+	conn.sendTicker(108)
+	quotes.expectQuote(t, "108.00")
+}
@@ -0,0 +1,397 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/consts"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bybitCategorySpot   = "spot"
+	bybitCategoryLinear = "linear"
+
+	// Bybit drops connections that have not sent an application level ping for a while
+	// and recommends one every 20 seconds.
+	bybitPingInterval = 20 * time.Second
+
+	// Spot accepts at most 10 args per subscribe request.
+	bybitSpotArgsPerRequest = 10
+
+	// Bybit limits the total length of the args subscribed on one connection to 21000
+	// characters; 200 ticker topics keeps every connection well under it.
+	bybitMaxTopicsPerConnection = 200
+)
+
+// Bybit answers every ping with a pong, a connection that left three of them
+// unanswered is dropped.
+var bybitConnectionOptions = websockets.Options{
+	Backoff:     websockets.DefaultBackoffPolicy,
+	ReadTimeout: 3 * bybitPingInterval,
+}
+
+type BybitPoller struct {
+	dataSource         entities.DataSource
+	cryptoQuotesWriter repositories.CryptoQuotesWriter
+	logger             *slog.Logger
+}
+
+type BybitOperationMessage struct {
+	ReqId string   `json:"req_id,omitempty"`
+	Op    string   `json:"op"`
+	Args  []string `json:"args,omitempty"`
+}
+
+/*
+{
+   "success": true,
+   "ret_msg": "",
+   "conn_id": "2324d924-aa4d-45b0-a858-7b8be29ab52b",
+   "req_id": "10001",
+   "op": "subscribe"
+}
+*/
+
+type BybitOperationResponse struct {
+	Success bool   `json:"success"`
+	RetMsg  string `json:"ret_msg"`
+	ConnId  string `json:"conn_id"`
+	ReqId   string `json:"req_id"`
+	Op      string `json:"op"`
+}
+
+/*
+{
+   "topic": "tickers.BTCUSDT",
+   "type": "snapshot",
+   "ts": 1673853746003,
+   "cs": 2588407389,
+   "data": {...}
+}
+*/
+
+type BybitTickerMessage struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Ts    int64           `json:"ts"`
+	Data  BybitTickerData `json:"data"`
+}
+
+// BybitTickerData holds the union of the spot and linear ticker fields. Linear deltas
+// only carry the fields that changed, so absent fields are left empty.
+type BybitTickerData struct {
+	Symbol       string `json:"symbol"`
+	LastPrice    string `json:"lastPrice"`
+	PrevPrice24h string `json:"prevPrice24h"`
+	HighPrice24h string `json:"highPrice24h"`
+	LowPrice24h  string `json:"lowPrice24h"`
+	Volume24h    string `json:"volume24h"`
+	Turnover24h  string `json:"turnover24h"`
+	Price24hPcnt string `json:"price24hPcnt"`
+	Bid1Price    string `json:"bid1Price"`
+	Bid1Size     string `json:"bid1Size"`
+	Ask1Price    string `json:"ask1Price"`
+	Ask1Size     string `json:"ask1Size"`
+	MarkPrice    string `json:"markPrice"`
+	IndexPrice   string `json:"indexPrice"`
+}
+
+func init() {
+	pollers.Register(consts.ByBit, NewBybitPoller)
+}
+
+func NewBybitPoller(dataSource entities.DataSource,
+	writers pollers.Writers) pollers.QuotePoller {
+	return &BybitPoller{
+		dataSource:         dataSource,
+		cryptoQuotesWriter: writers.CryptoQuotes,
+		logger:             slog.With(logging.DataSourceKey, dataSource.Name),
+	}
+}
+
+func (bybitPoller *BybitPoller) Poll(ctx context.Context) error {
+	rateLimit := bybitPoller.dataSource.RateLimit
+	if rateLimit == 0 || rateLimit > bybitMaxTopicsPerConnection {
+		rateLimit = bybitMaxTopicsPerConnection
+	}
+
+	// Spot and linear tickers are served by different endpoints, so every category gets
+	// its own set of connections.
+	var chunks [][]entities.SymbolPair
+	for _, symbolPairs := range bybitPoller.pairsByCategory() {
+		chunks = append(chunks, pollers.ChunkSymbolPairs(symbolPairs, rateLimit)...)
+	}
+
+	return pollers.PollChunks(ctx, chunks, bybitPoller.pollSymbolChunk)
+}
+
+func (bybitPoller *BybitPoller) pairsByCategory() map[string][]entities.SymbolPair {
+	categories := make(map[string][]entities.SymbolPair)
+	for _, pair := range bybitPoller.dataSource.SymbolPairs {
+		category := bybitCategory(pair.Market)
+		categories[category] = append(categories[category], pair)
+	}
+	return categories
+}
+
+// bybitCategory maps our market to the Bybit v5 category; anything that is not a
+// perpetual or futures market is treated as spot.
+func bybitCategory(market entities.Market) string {
+	switch strings.ToLower(market.Name) {
+	case "linear", "perpetual", "futures":
+		return bybitCategoryLinear
+	default:
+		return bybitCategorySpot
+	}
+}
+
+// The data source connection string is the public endpoint root,
+// e.g. wss://stream.bybit.com/v5/public, and the category is appended to it.
+func (bybitPoller *BybitPoller) endpoint(category string) string {
+	return strings.TrimRight(bybitPoller.dataSource.ConnectionString, "/") + "/" + category
+}
+
+// pollSymbolChunk polls pairs of a single category, the one of the first pair.
+func (bybitPoller *BybitPoller) pollSymbolChunk(ctx context.Context, chunkId int, pairs []entities.SymbolPair) error {
+	category := bybitCategory(pairs[0].Market)
+
+	var topics []string
+	for _, pair := range pairs {
+		topics = append(topics, fmt.Sprintf("tickers.%s%s",
+			strings.ToUpper(pair.BaseSymbol.Name),
+			strings.ToUpper(pair.QuoteSymbol.Name)))
+	}
+
+	argsPerRequest := len(topics)
+	if category == bybitCategorySpot {
+		argsPerRequest = bybitSpotArgsPerRequest
+	}
+
+	var tickers map[string]BybitTickerData
+
+	connName := fmt.Sprintf("Bybit %s conn %d", category, chunkId)
+	logger := bybitPoller.logger.With(logging.ConnectionIdKey, connName)
+
+	conn := websockets.NewReconnectingConn(
+		connName,
+		bybitPoller.endpoint(category),
+		bybitConnectionOptions,
+		websockets.Handler{
+			Subscribe: func(ctx context.Context, conn *websockets.Conn) error {
+				logger.Info("Started Bybit connection", "category", category, logging.SymbolPairs(pairs))
+
+				// A new connection starts over with snapshots.
+				tickers = make(map[string]BybitTickerData)
+
+				if err := bybitPoller.sendOperation(conn, "subscribe", topics, argsPerRequest); err != nil {
+					return err
+				}
+				go bybitPoller.ping(ctx, conn)
+				return nil
+			},
+			HandleMessage: func(conn *websockets.Conn, message []byte, receivedAt time.Time) error {
+				bybitPoller.handleMessage(logger, message, receivedAt, topics, tickers, pairs)
+				return nil
+			},
+			Unsubscribe: func(conn *websockets.Conn) {
+				if err := bybitPoller.sendOperation(conn, "unsubscribe", topics, argsPerRequest); err != nil {
+					logger.Warn("Error sending Bybit unsubscribe message", logging.Error(err))
+				}
+				logger.Info("Closed Bybit topics", "topics", topics)
+			},
+		})
+
+	return conn.Run(ctx)
+}
+
+func (bybitPoller *BybitPoller) handleMessage(logger *slog.Logger, message []byte, receivedAt time.Time,
+	topics []string, tickers map[string]BybitTickerData, pairs []entities.SymbolPair) {
+	var opResp BybitOperationResponse
+	if err := json.Unmarshal(message, &opResp); err == nil && opResp.Op != "" {
+		switch opResp.Op {
+		case "subscribe":
+			if opResp.Success {
+				logger.Info("Subscribed to Bybit topics", "topics", topics)
+			} else {
+				logger.Error("Bybit subscription failed", "reason", opResp.RetMsg)
+			}
+		case "unsubscribe", "ping", "pong":
+		default:
+			logger.Warn("Unhandled Bybit operation response", "message", string(message))
+		}
+		return
+	}
+
+	var tickerMsg BybitTickerMessage
+	if err := json.Unmarshal(message, &tickerMsg); err != nil {
+		logger.Warn("Error unmarshaling Bybit message", logging.Error(err))
+		return
+	}
+
+	if !strings.HasPrefix(tickerMsg.Topic, "tickers.") {
+		logger.Warn("Unhandled Bybit topic", "topic", tickerMsg.Topic)
+		return
+	}
+
+	if tickerMsg.Ts != 0 {
+		metrics.ObserveFeedLatency(bybitPoller.dataSource.Name, time.UnixMilli(tickerMsg.Ts), receivedAt)
+	}
+
+	symbol := strings.TrimPrefix(tickerMsg.Topic, "tickers.")
+	ticker := tickerMsg.Data
+	if tickerMsg.Type == "delta" {
+		previous, ok := tickers[symbol]
+		if !ok {
+			logger.Warn("Bybit delta received before snapshot", "symbol", symbol)
+			return
+		}
+		ticker = mergeBybitTicker(previous, ticker)
+	}
+	tickers[symbol] = ticker
+
+	quote, err := bybitPoller.tickerToCryptoQuote(symbol, ticker, tickerMsg.Ts, pairs)
+	if err != nil {
+		logger.Warn("Error converting Bybit ticker to quote", "symbol", symbol, logging.Error(err))
+		return
+	}
+	quote.ReceivedAt = receivedAt
+
+	err = bybitPoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
+	if err != nil {
+		logger.Error("Error writing Bybit quote", logging.SymbolPair(quote.SymbolPair), logging.Error(err))
+		return
+	}
+
+	logger.Debug("Wrote Bybit quote", logging.SymbolPair(quote.SymbolPair), "rate", quote.Rate)
+}
+
+// sendOperation sends the topics in requests of at most argsPerRequest args.
+func (bybitPoller *BybitPoller) sendOperation(conn *websockets.Conn, op string, topics []string, argsPerRequest int) error {
+	for i := 0; i < len(topics); i += argsPerRequest {
+		end := i + argsPerRequest
+		if end > len(topics) {
+			end = len(topics)
+		}
+
+		opMsg := BybitOperationMessage{
+			ReqId: op + strconv.Itoa(i),
+			Op:    op,
+			Args:  topics[i:end],
+		}
+
+		if err := conn.WriteJSON(opMsg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ping keeps the connection alive until ctx is cancelled or the connection is closed.
+func (bybitPoller *BybitPoller) ping(ctx context.Context, conn *websockets.Conn) {
+	ticker := time.NewTicker(bybitPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := conn.WriteJSON(BybitOperationMessage{Op: "ping"}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// mergeBybitTicker applies a delta on top of the last known ticker state.
+func mergeBybitTicker(previous BybitTickerData, delta BybitTickerData) BybitTickerData {
+	merged := previous
+	mergeField := func(field *string, value string) {
+		if value != "" {
+			*field = value
+		}
+	}
+
+	mergeField(&merged.LastPrice, delta.LastPrice)
+	mergeField(&merged.PrevPrice24h, delta.PrevPrice24h)
+	mergeField(&merged.HighPrice24h, delta.HighPrice24h)
+	mergeField(&merged.LowPrice24h, delta.LowPrice24h)
+	mergeField(&merged.Volume24h, delta.Volume24h)
+	mergeField(&merged.Turnover24h, delta.Turnover24h)
+	mergeField(&merged.Price24hPcnt, delta.Price24hPcnt)
+	mergeField(&merged.Bid1Price, delta.Bid1Price)
+	mergeField(&merged.Bid1Size, delta.Bid1Size)
+	mergeField(&merged.Ask1Price, delta.Ask1Price)
+	mergeField(&merged.Ask1Size, delta.Ask1Size)
+	mergeField(&merged.MarkPrice, delta.MarkPrice)
+	mergeField(&merged.IndexPrice, delta.IndexPrice)
+
+	return merged
+}
+
+func (bybitPoller *BybitPoller) tickerToCryptoQuote(symbol string, ticker BybitTickerData, ts int64, pairs []entities.SymbolPair) (entities.CryptoQuote, error) {
+	var quote entities.CryptoQuote
+
+	pair, err := bybitPoller.findSymbolPair(symbol, pairs)
+	if err != nil {
+		return quote, err
+	}
+
+	rate, err := pair.ParsePrice(ticker.LastPrice)
+	if err != nil {
+		return quote, fmt.Errorf("invalid last price: %w", err)
+	}
+	fields := tickerFields{pair: pair}
+	openRate := fields.price("open price", ticker.PrevPrice24h)
+	highRate := fields.price("high price", ticker.HighPrice24h)
+	lowRate := fields.price("low price", ticker.LowPrice24h)
+	closeRate := rate
+	volume := fields.quantity("volume", ticker.Volume24h)
+	quoteVolume := fields.quantity("turnover", ticker.Turnover24h)
+	bidRate := fields.price("bid price", ticker.Bid1Price)
+	bidSize := fields.quantity("bid size", ticker.Bid1Size)
+	askRate := fields.price("ask price", ticker.Ask1Price)
+	askSize := fields.quantity("ask size", ticker.Ask1Size)
+	if err := fields.err(); err != nil {
+		return quote, err
+	}
+
+	quote = entities.CryptoQuote{
+		SymbolPair:  pair,
+		Market:      pair.Market,
+		TimeStamp:   time.UnixMilli(ts),
+		Rate:        rate,
+		OpenRate:    openRate,
+		HighRate:    highRate,
+		LowRate:     lowRate,
+		CloseRate:   closeRate,
+		Volume:      volume,
+		QuoteVolume: quoteVolume,
+		BidRate:     bidRate,
+		BidSize:     bidSize,
+		AskRate:     askRate,
+		AskSize:     askSize,
+	}
+
+	return quote, nil
+}
+
+func (bybitPoller *BybitPoller) findSymbolPair(symbol string, pairs []entities.SymbolPair) (entities.SymbolPair, error) {
+	for _, pair := range pairs {
+		combined := strings.ToUpper(pair.BaseSymbol.Name + pair.QuoteSymbol.Name)
+		if combined == symbol {
+			return pair, nil
+		}
+	}
+	return entities.SymbolPair{}, fmt.Errorf("symbol pair not found for %s", symbol)
+}
@@ -2,15 +2,298 @@ package cryptocurrencyexchanges
 
 import (
 	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/consts"
 	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 )
 
+// geminiCandlesSubscription is the daily candle stream the open/high/low/volume are taken from.
+const geminiCandlesSubscription = "candles_1d"
+
+// Gemini sends a heartbeat every 5 seconds, so 30 seconds of silence mean the
+// connection is gone.
+var geminiConnectionOptions = websockets.Options{
+	Backoff:     websockets.DefaultBackoffPolicy,
+	ReadTimeout: 30 * time.Second,
+}
+
 type GenimiPoller struct {
-	dataSource entities.DataSource
+	dataSource         entities.DataSource
+	cryptoQuotesWriter repositories.CryptoQuotesWriter
+	logger             *slog.Logger
+}
+
+type GeminiSubscribeMessage struct {
+	Type          string                    `json:"type"`
+	Subscriptions []GeminiSubscriptionEntry `json:"subscriptions"`
+}
+
+type GeminiSubscriptionEntry struct {
+	Name    string   `json:"name"`
+	Symbols []string `json:"symbols"`
+}
+
+/*
+{
+   "type": "l2_updates",
+   "symbol": "BTCUSD",
+   "changes": [["buy", "9122.04", "0.00121425"]],
+   "trades": [{"type": "trade", "symbol": "BTCUSD", "event_id": 169841458, "timestamp": 1560976400428, "price": "9122.04", "quantity": "0.0073173", "side": "sell"}]
+}
+
+{
+   "type": "candles_1d_updates",
+   "symbol": "BTCUSD",
+   "changes": [[1561054500000, 9350.18, 9358.35, 9350.18, 9355.51, 2.07]]
+}
+*/
+
+type GeminiMarketDataMessage struct {
+	Type    string            `json:"type"`
+	Symbol  string            `json:"symbol"`
+	Trades  []GeminiTrade     `json:"trades"`
+	Result  string            `json:"result"`
+	Reason  string            `json:"reason"`
+	Changes []json.RawMessage `json:"changes"`
 }
 
-func NewGenimiPoller(dataSource entities.DataSource) pollers.QuotePoller {
-	return &GenimiPoller{dataSource: dataSource}
+type GeminiTrade struct {
+	Type      string `json:"type"`
+	Symbol    string `json:"symbol"`
+	EventId   int64  `json:"event_id"`
+	Timestamp int64  `json:"timestamp"`
+	Price     string `json:"price"`
+	Quantity  string `json:"quantity"`
+	Side      string `json:"side"`
 }
 
-func (geminiPoller *GenimiPoller) Poll() {}
+// GeminiTickerData is the ticker state assembled from the l2 trades and the daily candles.
+type GeminiTickerData struct {
+	LastPrice string
+	Open      string
+	High      string
+	Low       string
+	Volume    string
+	// Timestamp is the time of the last trade or when the last candle update arrived.
+	Timestamp int64
+}
+
+func init() {
+	pollers.Register(consts.Gemini, NewGenimiPoller)
+}
+
+func NewGenimiPoller(dataSource entities.DataSource,
+	writers pollers.Writers) pollers.QuotePoller {
+	return &GenimiPoller{
+		dataSource:         dataSource,
+		cryptoQuotesWriter: writers.CryptoQuotes,
+		logger:             slog.With(logging.DataSourceKey, dataSource.Name),
+	}
+}
+
+func (geminiPoller *GenimiPoller) Poll(ctx context.Context) error {
+	chunks := pollers.ChunkSymbolPairs(geminiPoller.dataSource.SymbolPairs, geminiPoller.dataSource.RateLimit)
+	return pollers.PollChunks(ctx, chunks, geminiPoller.pollSymbolChunk)
+}
+
+func (geminiPoller *GenimiPoller) pollSymbolChunk(ctx context.Context, chunkId int, pairs []entities.SymbolPair) error {
+	var symbols []string
+	for _, pair := range pairs {
+		symbols = append(symbols, strings.ToUpper(pair.BaseSymbol.Name+pair.QuoteSymbol.Name))
+	}
+
+	subMsg := GeminiSubscribeMessage{
+		Type: "subscribe",
+		Subscriptions: []GeminiSubscriptionEntry{
+			{Name: "l2", Symbols: symbols},
+			{Name: geminiCandlesSubscription, Symbols: symbols},
+		},
+	}
+
+	tickers := make(map[string]GeminiTickerData)
+
+	connName := fmt.Sprintf("Gemini conn %d", chunkId)
+	logger := geminiPoller.logger.With(logging.ConnectionIdKey, connName)
+
+	conn := websockets.NewReconnectingConn(
+		connName,
+		geminiPoller.dataSource.ConnectionString,
+		geminiConnectionOptions,
+		websockets.Handler{
+			Subscribe: func(ctx context.Context, conn *websockets.Conn) error {
+				logger.Info("Started Gemini connection", logging.SymbolPairs(pairs))
+				if err := conn.WriteJSON(subMsg); err != nil {
+					return err
+				}
+				logger.Info("Subscribed to Gemini market data", "symbols", symbols)
+				return nil
+			},
+			HandleMessage: func(conn *websockets.Conn, message []byte, receivedAt time.Time) error {
+				geminiPoller.handleMessage(logger, message, receivedAt, tickers, pairs)
+				return nil
+			},
+			Unsubscribe: func(conn *websockets.Conn) {
+				unsubMsg := subMsg
+				unsubMsg.Type = "unsubscribe"
+				if err := conn.WriteJSON(unsubMsg); err != nil {
+					logger.Warn("Error sending Gemini unsubscribe message", logging.Error(err))
+				}
+				logger.Info("Closed Gemini market data", "symbols", symbols)
+			},
+		})
+
+	return conn.Run(ctx)
+}
+
+func (geminiPoller *GenimiPoller) handleMessage(logger *slog.Logger, message []byte, receivedAt time.Time,
+	tickers map[string]GeminiTickerData, pairs []entities.SymbolPair) {
+	var dataMsg GeminiMarketDataMessage
+	if err := json.Unmarshal(message, &dataMsg); err != nil {
+		logger.Warn("Error unmarshaling Gemini message", logging.Error(err))
+		return
+	}
+
+	ticker := tickers[dataMsg.Symbol]
+
+	switch dataMsg.Type {
+	case "heartbeat":
+		return
+	case "l2_updates":
+		// The initial l2 snapshot carries the most recent trades, oldest first. They are
+		// historical, so they tell nothing about the latency of the feed.
+		if len(dataMsg.Trades) == 0 {
+			return
+		}
+		lastTrade := dataMsg.Trades[len(dataMsg.Trades)-1]
+		ticker.LastPrice = lastTrade.Price
+		ticker.Timestamp = lastTrade.Timestamp
+	case "trade":
+		var trade GeminiTrade
+		if err := json.Unmarshal(message, &trade); err != nil {
+			logger.Warn("Error unmarshaling Gemini trade", "symbol", dataMsg.Symbol, logging.Error(err))
+			return
+		}
+		ticker.LastPrice = trade.Price
+		ticker.Timestamp = trade.Timestamp
+		metrics.ObserveFeedLatency(geminiPoller.dataSource.Name, time.UnixMilli(trade.Timestamp), receivedAt)
+	case geminiCandlesSubscription + "_updates":
+		if err := applyGeminiCandle(&ticker, dataMsg.Changes, receivedAt); err != nil {
+			logger.Warn("Error parsing Gemini candle", "symbol", dataMsg.Symbol, logging.Error(err))
+			return
+		}
+	default:
+		if dataMsg.Result == "error" {
+			logger.Error("Gemini error", "reason", dataMsg.Reason)
+		} else {
+			logger.Warn("Unhandled Gemini message type", "type", dataMsg.Type)
+		}
+		return
+	}
+
+	tickers[dataMsg.Symbol] = ticker
+
+	if ticker.LastPrice == "" || ticker.High == "" {
+		// Wait until both streams have delivered data for the symbol.
+		return
+	}
+
+	quote, err := geminiPoller.tickerToCryptoQuote(dataMsg.Symbol, ticker, pairs)
+	if err != nil {
+		logger.Warn("Error converting Gemini ticker to quote", "symbol", dataMsg.Symbol, logging.Error(err))
+		return
+	}
+	quote.ReceivedAt = receivedAt
+
+	err = geminiPoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
+	if err != nil {
+		logger.Error("Error writing Gemini quote", logging.SymbolPair(quote.SymbolPair), logging.Error(err))
+		return
+	}
+
+	logger.Debug("Wrote Gemini quote", logging.SymbolPair(quote.SymbolPair), "rate", quote.Rate)
+}
+
+// applyGeminiCandle takes the newest candle of an update, laid out as
+// [time, open, high, low, close, volume], into the ticker state. The quote it produces
+// is stamped with receivedAt, the time of the candle is the start of its day and
+// repeating the time of the last trade would store two quotes with the same timestamp.
+func applyGeminiCandle(ticker *GeminiTickerData, changes []json.RawMessage, receivedAt time.Time) error {
+	if len(changes) == 0 {
+		return fmt.Errorf("empty candle update")
+	}
+
+	var candle []json.Number
+	if err := json.Unmarshal(changes[0], &candle); err != nil {
+		return err
+	}
+	if len(candle) < 6 {
+		return fmt.Errorf("unexpected candle length %d", len(candle))
+	}
+
+	ticker.Open = candle[1].String()
+	ticker.High = candle[2].String()
+	ticker.Low = candle[3].String()
+	ticker.Volume = candle[5].String()
+	if ticker.LastPrice == "" {
+		ticker.LastPrice = candle[4].String()
+	}
+	ticker.Timestamp = receivedAt.UnixMilli()
+
+	return nil
+}
+
+func (geminiPoller *GenimiPoller) tickerToCryptoQuote(symbol string, ticker GeminiTickerData, pairs []entities.SymbolPair) (entities.CryptoQuote, error) {
+	var quote entities.CryptoQuote
+
+	pair, err := geminiPoller.findSymbolPair(symbol, pairs)
+	if err != nil {
+		return quote, err
+	}
+
+	rate, err := pair.ParsePrice(ticker.LastPrice)
+	if err != nil {
+		return quote, fmt.Errorf("invalid last price: %w", err)
+	}
+	fields := tickerFields{pair: pair}
+	openRate := fields.price("open price", ticker.Open)
+	highRate := fields.price("high price", ticker.High)
+	lowRate := fields.price("low price", ticker.Low)
+	closeRate := rate
+	volume := fields.quantity("volume", ticker.Volume)
+	if err := fields.err(); err != nil {
+		return quote, err
+	}
+
+	quote = entities.CryptoQuote{
+		SymbolPair: pair,
+		Market:     pair.Market,
+		TimeStamp:  time.UnixMilli(ticker.Timestamp),
+		Rate:       rate,
+		OpenRate:   openRate,
+		HighRate:   highRate,
+		LowRate:    lowRate,
+		CloseRate:  closeRate,
+		Volume:     volume,
+	}
+
+	return quote, nil
+}
+
+func (geminiPoller *GenimiPoller) findSymbolPair(symbol string, pairs []entities.SymbolPair) (entities.SymbolPair, error) {
+	for _, pair := range pairs {
+		combined := strings.ToUpper(pair.BaseSymbol.Name + pair.QuoteSymbol.Name)
+		if combined == symbol {
+			return pair, nil
+		}
+	}
+	return entities.SymbolPair{}, fmt.Errorf("symbol pair not found for %s", symbol)
+}
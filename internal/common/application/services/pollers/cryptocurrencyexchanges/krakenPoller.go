@@ -0,0 +1,293 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/domain/consts"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/websockets"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// krakenAssetAliases maps Kraken's legacy asset codes to the names used in tds.symbols.
+var krakenAssetAliases = map[string]string{
+	"XBT": "BTC",
+	"XDG": "DOGE",
+}
+
+// Kraken sends a heartbeat whenever a second passes without other messages, so 30
+// seconds of silence mean the connection is gone.
+var krakenConnectionOptions = websockets.Options{
+	Backoff:     websockets.DefaultBackoffPolicy,
+	ReadTimeout: 30 * time.Second,
+}
+
+type KrakenPoller struct {
+	dataSource         entities.DataSource
+	cryptoQuotesWriter repositories.CryptoQuotesWriter
+	logger             *slog.Logger
+}
+
+type KrakenSubscribeMessage struct {
+	Method string                `json:"method"`
+	Params KrakenSubscribeParams `json:"params"`
+	ReqId  int                   `json:"req_id"`
+}
+
+type KrakenSubscribeParams struct {
+	Channel string   `json:"channel"`
+	Symbol  []string `json:"symbol"`
+}
+
+/*
+{
+   "method": "subscribe",
+   "req_id": 1,
+   "result": {"channel": "ticker", "snapshot": true, "symbol": "BTC/USD"},
+   "success": true,
+   "error": "..."
+}
+*/
+
+type KrakenSubscriptionResponse struct {
+	Method  string `json:"method"`
+	ReqId   int    `json:"req_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error"`
+	Result  struct {
+		Channel string `json:"channel"`
+		Symbol  string `json:"symbol"`
+	} `json:"result"`
+}
+
+/*
+{
+   "channel": "ticker",
+   "type": "snapshot",
+   "data": [{
+      "symbol": "BTC/USD",
+      "bid": 63150.1, "bid_qty": 0.5,
+      "ask": 63150.2, "ask_qty": 1.2,
+      "last": 63150.2, "volume": 1534.8,
+      "vwap": 62890.4, "low": 62010.0, "high": 63500.0,
+      "change": 901.3, "change_pct": 1.45
+   }]
+}
+*/
+
+type KrakenChannelMessage struct {
+	Channel string            `json:"channel"`
+	Type    string            `json:"type"`
+	Data    []json.RawMessage `json:"data"`
+}
+
+type KrakenTickerData struct {
+	Symbol    string      `json:"symbol"`
+	Bid       json.Number `json:"bid"`
+	BidQty    json.Number `json:"bid_qty"`
+	Ask       json.Number `json:"ask"`
+	AskQty    json.Number `json:"ask_qty"`
+	Last      json.Number `json:"last"`
+	Volume    json.Number `json:"volume"`
+	Vwap      json.Number `json:"vwap"`
+	Low       json.Number `json:"low"`
+	High      json.Number `json:"high"`
+	Change    json.Number `json:"change"`
+	ChangePct json.Number `json:"change_pct"`
+}
+
+func init() {
+	pollers.Register(consts.Kraken, NewKrakenPoller)
+}
+
+func NewKrakenPoller(dataSource entities.DataSource,
+	writers pollers.Writers) pollers.QuotePoller {
+	return &KrakenPoller{
+		dataSource:         dataSource,
+		cryptoQuotesWriter: writers.CryptoQuotes,
+		logger:             slog.With(logging.DataSourceKey, dataSource.Name),
+	}
+}
+
+func (krakenPoller *KrakenPoller) Poll(ctx context.Context) error {
+	chunks := pollers.ChunkSymbolPairs(krakenPoller.dataSource.SymbolPairs, krakenPoller.dataSource.RateLimit)
+	return pollers.PollChunks(ctx, chunks, krakenPoller.pollSymbolChunk)
+}
+
+func (krakenPoller *KrakenPoller) pollSymbolChunk(ctx context.Context, chunkId int, pairs []entities.SymbolPair) error {
+	var symbols []string
+	for _, pair := range pairs {
+		symbols = append(symbols, fmt.Sprintf("%s/%s",
+			strings.ToUpper(pair.BaseSymbol.Name),
+			strings.ToUpper(pair.QuoteSymbol.Name)))
+	}
+
+	subMsg := KrakenSubscribeMessage{
+		Method: "subscribe",
+		Params: KrakenSubscribeParams{
+			Channel: "ticker",
+			Symbol:  symbols,
+		},
+		ReqId: 1,
+	}
+
+	connName := fmt.Sprintf("Kraken conn %d", chunkId)
+	logger := krakenPoller.logger.With(logging.ConnectionIdKey, connName)
+
+	conn := websockets.NewReconnectingConn(
+		connName,
+		krakenPoller.dataSource.ConnectionString,
+		krakenConnectionOptions,
+		websockets.Handler{
+			Subscribe: func(ctx context.Context, conn *websockets.Conn) error {
+				logger.Info("Started Kraken connection", logging.SymbolPairs(pairs))
+				return conn.WriteJSON(subMsg)
+			},
+			HandleMessage: func(conn *websockets.Conn, message []byte, receivedAt time.Time) error {
+				krakenPoller.handleMessage(logger, message, receivedAt)
+				return nil
+			},
+			Unsubscribe: func(conn *websockets.Conn) {
+				unsubMsg := subMsg
+				unsubMsg.Method = "unsubscribe"
+				unsubMsg.ReqId = 2
+				if err := conn.WriteJSON(unsubMsg); err != nil {
+					logger.Warn("Error sending Kraken unsubscribe message", logging.Error(err))
+				}
+				logger.Info("Closed Kraken ticker subscriptions", "symbols", symbols)
+			},
+		})
+
+	return conn.Run(ctx)
+}
+
+func (krakenPoller *KrakenPoller) handleMessage(logger *slog.Logger, message []byte, receivedAt time.Time) {
+	var subResp KrakenSubscriptionResponse
+	if err := json.Unmarshal(message, &subResp); err == nil && subResp.Method != "" {
+		if subResp.Method != "subscribe" {
+			return
+		}
+		if subResp.Success {
+			logger.Info("Subscribed to Kraken ticker", "symbol", subResp.Result.Symbol)
+		} else {
+			logger.Error("Kraken subscription failed", "reason", subResp.Error)
+		}
+		return
+	}
+
+	var channelMsg KrakenChannelMessage
+	if err := json.Unmarshal(message, &channelMsg); err != nil {
+		logger.Warn("Error unmarshaling Kraken message", logging.Error(err))
+		return
+	}
+
+	switch channelMsg.Channel {
+	case "heartbeat", "status":
+		return
+	case "ticker":
+	default:
+		logger.Warn("Unhandled Kraken channel", "channel", channelMsg.Channel)
+		return
+	}
+
+	for _, data := range channelMsg.Data {
+		var tickerData KrakenTickerData
+		if err := json.Unmarshal(data, &tickerData); err != nil {
+			logger.Warn("Error unmarshaling Kraken ticker", logging.Error(err))
+			continue
+		}
+
+		quote, err := krakenPoller.tickerToCryptoQuote(tickerData, krakenPoller.dataSource)
+		if err != nil {
+			logger.Warn("Error converting Kraken ticker to quote", "symbol", tickerData.Symbol, logging.Error(err))
+			continue
+		}
+		// The Kraken ticker carries no exchange time.
+		quote.TimeStamp = receivedAt
+		quote.ReceivedAt = receivedAt
+
+		err = krakenPoller.cryptoQuotesWriter.Write([]entities.CryptoQuote{quote})
+		if err != nil {
+			logger.Error("Error writing Kraken quote", logging.SymbolPair(quote.SymbolPair), logging.Error(err))
+			continue
+		}
+
+		logger.Debug("Wrote Kraken quote", logging.SymbolPair(quote.SymbolPair), "rate", quote.Rate)
+	}
+}
+
+func (krakenPoller *KrakenPoller) tickerToCryptoQuote(ticker KrakenTickerData, dataSource entities.DataSource) (entities.CryptoQuote, error) {
+	var quote entities.CryptoQuote
+
+	pair, err := krakenPoller.findSymbolPair(ticker.Symbol, dataSource.SymbolPairs)
+	if err != nil {
+		return quote, err
+	}
+
+	rate, err := pair.ParsePrice(ticker.Last.String())
+	if err != nil {
+		return quote, fmt.Errorf("invalid last price: %w", err)
+	}
+
+	// Kraken has no open price in the ticker, so it is derived from the 24h change.
+	fields := tickerFields{pair: pair}
+	openRate := fields.openRate(rate, ticker.Change.String())
+	highRate := fields.price("high price", ticker.High.String())
+	lowRate := fields.price("low price", ticker.Low.String())
+	closeRate := rate
+	volume := fields.quantity("volume", ticker.Volume.String())
+	bidRate := fields.price("bid price", ticker.Bid.String())
+	bidSize := fields.quantity("bid size", ticker.BidQty.String())
+	askRate := fields.price("ask price", ticker.Ask.String())
+	askSize := fields.quantity("ask size", ticker.AskQty.String())
+	if err := fields.err(); err != nil {
+		return quote, err
+	}
+
+	quote = entities.CryptoQuote{
+		SymbolPair: pair,
+		Market:     pair.Market,
+		Rate:       rate,
+		OpenRate:   openRate,
+		HighRate:   highRate,
+		LowRate:    lowRate,
+		CloseRate:  closeRate,
+		Volume:     volume,
+		BidRate:    bidRate,
+		BidSize:    bidSize,
+		AskRate:    askRate,
+		AskSize:    askSize,
+	}
+
+	return quote, nil
+}
+
+func (krakenPoller *KrakenPoller) findSymbolPair(symbol string, pairs []entities.SymbolPair) (entities.SymbolPair, error) {
+	base, quoteAsset, found := strings.Cut(symbol, "/")
+	if !found {
+		return entities.SymbolPair{}, fmt.Errorf("malformed Kraken symbol %s", symbol)
+	}
+
+	base = krakenAssetName(base)
+	quoteAsset = krakenAssetName(quoteAsset)
+
+	for _, pair := range pairs {
+		if strings.EqualFold(pair.BaseSymbol.Name, base) && strings.EqualFold(pair.QuoteSymbol.Name, quoteAsset) {
+			return pair, nil
+		}
+	}
+	return entities.SymbolPair{}, fmt.Errorf("symbol pair not found for %s", symbol)
+}
+
+func krakenAssetName(asset string) string {
+	if name, ok := krakenAssetAliases[strings.ToUpper(asset)]; ok {
+		return name
+	}
+	return asset
+}
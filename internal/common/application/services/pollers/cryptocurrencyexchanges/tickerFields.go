@@ -0,0 +1,54 @@
+package cryptocurrencyexchanges
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"errors"
+	"fmt"
+)
+
+// tickerFields parses the fields of a ticker at the precisions of its symbol pair.
+// Empty values are fields the exchange does not send and stay zero, which the writers
+// store as null. Any other value that does not parse or fit the precision fails the
+// whole quote, rather than being stored as zero.
+type tickerFields struct {
+	pair entities.SymbolPair
+	errs []error
+}
+
+func (fields *tickerFields) price(name string, value string) entities.Decimal {
+	if value == "" {
+		return entities.Decimal{}
+	}
+	price, err := fields.pair.ParsePrice(value)
+	if err != nil {
+		fields.errs = append(fields.errs, fmt.Errorf("invalid %s: %w", name, err))
+	}
+	return price
+}
+
+func (fields *tickerFields) quantity(name string, value string) entities.Decimal {
+	if value == "" {
+		return entities.Decimal{}
+	}
+	quantity, err := fields.pair.ParseQuantity(value)
+	if err != nil {
+		fields.errs = append(fields.errs, fmt.Errorf("invalid %s: %w", name, err))
+	}
+	return quantity
+}
+
+// openRate derives the open price of exchanges that only send the change since then.
+func (fields *tickerFields) openRate(rate entities.Decimal, change string) entities.Decimal {
+	if change == "" {
+		return entities.Decimal{}
+	}
+	openRate, err := rate.Sub(fields.price("change", change))
+	if err != nil {
+		fields.errs = append(fields.errs, fmt.Errorf("invalid open price: %w", err))
+	}
+	return openRate
+}
+
+func (fields *tickerFields) err() error {
+	return errors.Join(fields.errs...)
+}
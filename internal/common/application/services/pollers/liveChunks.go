@@ -0,0 +1,205 @@
+package pollers
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"context"
+	"errors"
+	"sync"
+)
+
+// LiveChunk is the connection of one chunk of pairs. Add and Remove are called while
+// Run is serving and have to subscribe or unsubscribe the pairs on the live connection
+// as well as change what a reconnect subscribes.
+type LiveChunk struct {
+	Run    func(ctx context.Context) error
+	Add    func(pairs []entities.SymbolPair)
+	Remove func(pairs []entities.SymbolPair)
+}
+
+// LiveChunks polls chunks of pairs like PollChunks, but follows changes of the pairs
+// while running: removed pairs are taken off their chunk, added pairs fill up chunks
+// with room before new chunks are started. Chunks that run out of pairs stay open and
+// are filled up first.
+type LiveChunks struct {
+	mu        sync.Mutex
+	chunkSize int
+	newChunk  func(chunkId int, pairs []entities.SymbolPair) LiveChunk
+	pairs     []entities.SymbolPair
+	chunks    []*liveChunk
+
+	ctx      context.Context
+	running  int
+	stopped  bool
+	finished chan struct{}
+	errs     []error
+}
+
+type liveChunk struct {
+	chunk   LiveChunk
+	pairIds map[int]bool
+}
+
+// NewLiveChunks splits pairs into chunks of at most chunkSize pairs, a chunkSize of 0
+// keeps all pairs in a single chunk. newChunk creates the connection of a chunk, which
+// is numbered from 0 in the order the chunks are started.
+func NewLiveChunks(pairs []entities.SymbolPair, chunkSize int,
+	newChunk func(chunkId int, pairs []entities.SymbolPair) LiveChunk) *LiveChunks {
+	return &LiveChunks{
+		chunkSize: chunkSize,
+		newChunk:  newChunk,
+		pairs:     pairs,
+		finished:  make(chan struct{}),
+	}
+}
+
+// Run starts a chunk for every chunkSize pairs and waits until all chunks, including
+// the ones started by Update, have returned.
+func (chunks *LiveChunks) Run(ctx context.Context) error {
+	chunks.mu.Lock()
+	chunks.ctx = ctx
+	for _, pairs := range ChunkSymbolPairs(chunks.pairs, chunks.chunkSize) {
+		chunks.start(pairs)
+	}
+	if chunks.running == 0 {
+		chunks.stopped = true
+		close(chunks.finished)
+	}
+	chunks.mu.Unlock()
+
+	<-chunks.finished
+
+	chunks.mu.Lock()
+	defer chunks.mu.Unlock()
+	return errors.Join(chunks.errs...)
+}
+
+// Update makes pairs the pairs to poll. Pairs are matched by id, a pair whose precision
+// or lot size changed is removed from and added back to its chunk, so its channels and
+// books start over at the new precision.
+func (chunks *LiveChunks) Update(pairs []entities.SymbolPair) {
+	chunks.mu.Lock()
+	defer chunks.mu.Unlock()
+
+	added, removed, changed := DiffSymbolPairs(chunks.pairs, pairs)
+	chunks.pairs = pairs
+	if chunks.ctx == nil || chunks.stopped {
+		// Run has not started yet and picks the pairs up itself, or every chunk is done.
+		return
+	}
+
+	for _, chunk := range chunks.chunks {
+		var chunkRemoved []entities.SymbolPair
+		for _, pair := range removed {
+			if chunk.pairIds[pair.Id] {
+				delete(chunk.pairIds, pair.Id)
+				chunkRemoved = append(chunkRemoved, pair)
+			}
+		}
+		if len(chunkRemoved) > 0 {
+			chunk.chunk.Remove(chunkRemoved)
+		}
+
+		var chunkChanged []entities.SymbolPair
+		for _, pair := range changed {
+			if chunk.pairIds[pair.Id] {
+				chunkChanged = append(chunkChanged, pair)
+			}
+		}
+		if len(chunkChanged) > 0 {
+			chunk.chunk.Remove(chunkChanged)
+			chunk.chunk.Add(chunkChanged)
+		}
+	}
+
+	for _, chunk := range chunks.chunks {
+		if len(added) == 0 {
+			break
+		}
+		room := len(added)
+		if chunks.chunkSize > 0 {
+			room = min(room, chunks.chunkSize-len(chunk.pairIds))
+		}
+		if room <= 0 {
+			continue
+		}
+
+		for _, pair := range added[:room] {
+			chunk.pairIds[pair.Id] = true
+		}
+		chunk.chunk.Add(added[:room])
+		added = added[room:]
+	}
+
+	for _, pairs := range ChunkSymbolPairs(added, chunks.chunkSize) {
+		chunks.start(pairs)
+	}
+}
+
+// start runs a new chunk, chunks.mu has to be held.
+func (chunks *LiveChunks) start(pairs []entities.SymbolPair) {
+	chunk := &liveChunk{
+		chunk:   chunks.newChunk(len(chunks.chunks), pairs),
+		pairIds: make(map[int]bool, len(pairs)),
+	}
+	for _, pair := range pairs {
+		chunk.pairIds[pair.Id] = true
+	}
+	chunks.chunks = append(chunks.chunks, chunk)
+	chunks.running++
+
+	go func() {
+		err := chunk.chunk.Run(chunks.ctx)
+
+		chunks.mu.Lock()
+		defer chunks.mu.Unlock()
+		chunks.errs = append(chunks.errs, err)
+		chunks.running--
+		if chunks.running == 0 {
+			chunks.stopped = true
+			close(chunks.finished)
+		}
+	}()
+}
+
+// DiffSymbolPairs returns the pairs of next that are not in current, the pairs of
+// current that are not in next and the pairs of next whose precision or lot size differ
+// from their pair in current, matched by id.
+func DiffSymbolPairs(current []entities.SymbolPair, next []entities.SymbolPair) (added []entities.SymbolPair,
+	removed []entities.SymbolPair, changed []entities.SymbolPair) {
+	currentPairs := make(map[int]entities.SymbolPair, len(current))
+	for _, pair := range current {
+		currentPairs[pair.Id] = pair
+	}
+	nextIds := make(map[int]bool, len(next))
+	for _, pair := range next {
+		nextIds[pair.Id] = true
+		currentPair, found := currentPairs[pair.Id]
+		if !found {
+			added = append(added, pair)
+		} else if currentPair != pair {
+			changed = append(changed, pair)
+		}
+	}
+	for _, pair := range current {
+		if !nextIds[pair.Id] {
+			removed = append(removed, pair)
+		}
+	}
+	return added, removed, changed
+}
+
+// RemoveSymbolPairs returns a new slice with the pairs that are not in removed, matched
+// by id.
+func RemoveSymbolPairs(pairs []entities.SymbolPair, removed []entities.SymbolPair) []entities.SymbolPair {
+	removedIds := make(map[int]bool, len(removed))
+	for _, pair := range removed {
+		removedIds[pair.Id] = true
+	}
+	kept := make([]entities.SymbolPair, 0, len(pairs))
+	for _, pair := range pairs {
+		if !removedIds[pair.Id] {
+			kept = append(kept, pair)
+		}
+	}
+	return kept
+}
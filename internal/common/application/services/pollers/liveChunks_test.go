@@ -0,0 +1,222 @@
+package pollers
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func testPair(id int) entities.SymbolPair {
+	return entities.SymbolPair{Id: id, PricePrecision: 8, QuantityPrecision: 8}
+}
+
+func testPairs(ids ...int) []entities.SymbolPair {
+	pairs := make([]entities.SymbolPair, 0, len(ids))
+	for _, id := range ids {
+		pairs = append(pairs, testPair(id))
+	}
+	return pairs
+}
+
+func pairIds(pairs []entities.SymbolPair) []int {
+	ids := make([]int, 0, len(pairs))
+	for _, pair := range pairs {
+		ids = append(ids, pair.Id)
+	}
+	return ids
+}
+
+func TestDiffSymbolPairs(t *testing.T) {
+	finer := testPair(2)
+	finer.PricePrecision = 10
+	lotSize := testPair(3)
+	lotSize.LotSize = entities.Decimal{Unscaled: 1, Scale: 3}
+
+	tests := []struct {
+		name        string
+		current     []entities.SymbolPair
+		next        []entities.SymbolPair
+		wantAdded   []int
+		wantRemoved []int
+		wantChanged []int
+	}{
+		{name: "unchanged", current: testPairs(1, 2), next: testPairs(2, 1)},
+		{name: "from nothing", next: testPairs(1, 2), wantAdded: []int{1, 2}},
+		{name: "to nothing", current: testPairs(1, 2), wantRemoved: []int{1, 2}},
+		{name: "added and removed", current: testPairs(1, 2, 3), next: testPairs(2, 4), wantAdded: []int{4},
+			wantRemoved: []int{1, 3}},
+		{name: "precision changed", current: testPairs(1, 2), next: []entities.SymbolPair{testPair(1), finer},
+			wantChanged: []int{2}},
+		{name: "lot size changed", current: testPairs(3), next: []entities.SymbolPair{lotSize},
+			wantChanged: []int{3}},
+		{name: "changed and removed", current: testPairs(1, 2, 3), next: []entities.SymbolPair{finer, testPair(4)},
+			wantAdded: []int{4}, wantRemoved: []int{1, 3}, wantChanged: []int{2}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			added, removed, changed := DiffSymbolPairs(test.current, test.next)
+			if ids := pairIds(added); !slices.Equal(ids, test.wantAdded) {
+				t.Errorf("added %v, want %v", ids, test.wantAdded)
+			}
+			if ids := pairIds(removed); !slices.Equal(ids, test.wantRemoved) {
+				t.Errorf("removed %v, want %v", ids, test.wantRemoved)
+			}
+			if ids := pairIds(changed); !slices.Equal(ids, test.wantChanged) {
+				t.Errorf("changed %v, want %v", ids, test.wantChanged)
+			}
+		})
+	}
+}
+
+// fakeLiveChunks records the pairs and the Add and Remove calls of every chunk.
+type fakeLiveChunks struct {
+	mu      sync.Mutex
+	pairs   [][]entities.SymbolPair
+	calls   [][]string
+	started chan struct{}
+}
+
+func (fake *fakeLiveChunks) newChunk(chunkId int, pairs []entities.SymbolPair) LiveChunk {
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	fake.pairs = append(fake.pairs, slices.Clone(pairs))
+	fake.calls = append(fake.calls, nil)
+
+	return LiveChunk{
+		Run: func(ctx context.Context) error {
+			fake.started <- struct{}{}
+			<-ctx.Done()
+			return nil
+		},
+		Add: func(pairs []entities.SymbolPair) {
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			fake.pairs[chunkId] = append(fake.pairs[chunkId], pairs...)
+			fake.calls[chunkId] = append(fake.calls[chunkId], fmt.Sprint("add ", pairIds(pairs)))
+		},
+		Remove: func(pairs []entities.SymbolPair) {
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			fake.pairs[chunkId] = RemoveSymbolPairs(fake.pairs[chunkId], pairs)
+			fake.calls[chunkId] = append(fake.calls[chunkId], fmt.Sprint("remove ", pairIds(pairs)))
+		},
+	}
+}
+
+func TestLiveChunksUpdate(t *testing.T) {
+	finer := testPair(2)
+	finer.PricePrecision = 10
+
+	tests := []struct {
+		name      string
+		chunkSize int
+		pairs     []entities.SymbolPair
+		updates   [][]entities.SymbolPair
+		// wantChunks are the pair ids of every chunk in the order they were started.
+		wantChunks [][]int
+		// wantCalls are the Add and Remove calls of every chunk, nil to skip the check.
+		wantCalls [][]string
+	}{
+		{
+			name:       "added pairs fill up chunks with room first",
+			chunkSize:  2,
+			pairs:      testPairs(1, 2, 3),
+			updates:    [][]entities.SymbolPair{testPairs(1, 2, 3, 4, 5)},
+			wantChunks: [][]int{{1, 2}, {3, 4}, {5}},
+			wantCalls:  [][]string{nil, {"add [4]"}, nil},
+		},
+		{
+			name:       "removed pairs make room",
+			chunkSize:  2,
+			pairs:      testPairs(1, 2, 3),
+			updates:    [][]entities.SymbolPair{testPairs(2, 3, 4)},
+			wantChunks: [][]int{{2, 4}, {3}},
+			wantCalls:  [][]string{{"remove [1]", "add [4]"}, nil},
+		},
+		{
+			name:       "changed pairs are resubscribed on their chunk",
+			chunkSize:  2,
+			pairs:      testPairs(1, 2, 3),
+			updates:    [][]entities.SymbolPair{{testPair(1), finer, testPair(3)}},
+			wantChunks: [][]int{{1, 2}, {3}},
+			wantCalls:  [][]string{{"remove [2]", "add [2]"}, nil},
+		},
+		{
+			name:       "emptied chunks stay open and are filled first",
+			chunkSize:  2,
+			pairs:      testPairs(1, 2, 3),
+			updates:    [][]entities.SymbolPair{nil, testPairs(4, 5, 6)},
+			wantChunks: [][]int{{4, 5}, {6}},
+		},
+		{
+			name:       "single chunk takes every pair",
+			chunkSize:  0,
+			pairs:      testPairs(1, 2),
+			updates:    [][]entities.SymbolPair{testPairs(1, 2, 3, 4)},
+			wantChunks: [][]int{{1, 2, 3, 4}},
+			wantCalls:  [][]string{{"add [3 4]"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fake := &fakeLiveChunks{started: make(chan struct{}, 10)}
+			chunks := NewLiveChunks(test.pairs, test.chunkSize, fake.newChunk)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- chunks.Run(ctx) }()
+
+			select {
+			case <-fake.started:
+			case <-time.After(time.Second):
+				t.Fatal("no chunk started")
+			}
+			for _, pairs := range test.updates {
+				chunks.Update(pairs)
+			}
+
+			cancel()
+			select {
+			case err := <-done:
+				if err != nil {
+					t.Errorf("Run() failed: %v", err)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Run() did not return after cancelling")
+			}
+
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			var gotChunks [][]int
+			for _, pairs := range fake.pairs {
+				ids := pairIds(pairs)
+				slices.Sort(ids)
+				gotChunks = append(gotChunks, ids)
+			}
+			if !slices.EqualFunc(gotChunks, test.wantChunks, slices.Equal) {
+				t.Errorf("chunks %v, want %v", gotChunks, test.wantChunks)
+			}
+			if test.wantCalls != nil && !slices.EqualFunc(fake.calls, test.wantCalls, slices.Equal) {
+				t.Errorf("calls %q, want %q", fake.calls, test.wantCalls)
+			}
+			// Every chunk holds the pairs as they were last updated.
+			latest := make(map[int]entities.SymbolPair)
+			for _, pair := range test.updates[len(test.updates)-1] {
+				latest[pair.Id] = pair
+			}
+			for _, pairs := range fake.pairs {
+				for _, pair := range pairs {
+					if pair != latest[pair.Id] {
+						t.Errorf("chunk holds the outdated pair %+v", pair)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package pollers
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+const (
+	// OrderBookDepth is the number of levels per side written with every snapshot.
+	OrderBookDepth = 20
+	// OrderBookSnapshotInterval is how often the top of every synced book is written.
+	OrderBookSnapshotInterval = time.Second
+)
+
+// OrderBookSide holds the levels of one side of a book by price. Prices and quantities
+// are kept exactly as the exchange sent them.
+type OrderBookSide struct {
+	levels     map[entities.Decimal]entities.OrderBookLevel
+	descending bool
+}
+
+// Set replaces the quantity at the price, a zero quantity removes the level.
+func (side *OrderBookSide) Set(price entities.Decimal, quantity entities.Decimal) {
+	key := price.Normalize()
+	if quantity.IsZero() {
+		delete(side.levels, key)
+		return
+	}
+	side.levels[key] = entities.OrderBookLevel{Price: price, Quantity: quantity}
+}
+
+func (side *OrderBookSide) Len() int {
+	return len(side.levels)
+}
+
+// Top returns the best n levels, highest bids or lowest asks first.
+func (side *OrderBookSide) Top(n int) []entities.OrderBookLevel {
+	levels := make([]entities.OrderBookLevel, 0, len(side.levels))
+	for _, level := range side.levels {
+		levels = append(levels, level)
+	}
+
+	slices.SortFunc(levels, func(a, b entities.OrderBookLevel) int {
+		if side.descending {
+			return b.Price.Cmp(a.Price)
+		}
+		return a.Price.Cmp(b.Price)
+	})
+
+	if len(levels) > n {
+		levels = levels[:n]
+	}
+	return levels
+}
+
+// OrderBook is a level 2 order book of one symbol pair. It is not safe for concurrent
+// use, the exchange specific code guards it together with its sync state.
+type OrderBook struct {
+	Pair entities.SymbolPair
+	Bids *OrderBookSide
+	Asks *OrderBookSide
+}
+
+func NewOrderBook(pair entities.SymbolPair) *OrderBook {
+	book := &OrderBook{Pair: pair}
+	book.Clear()
+	return book
+}
+
+// Clear drops all levels, e.g. before a new snapshot is applied.
+func (book *OrderBook) Clear() {
+	book.Bids = &OrderBookSide{levels: make(map[entities.Decimal]entities.OrderBookLevel), descending: true}
+	book.Asks = &OrderBookSide{levels: make(map[entities.Decimal]entities.OrderBookLevel)}
+}
+
+// Snapshot returns the best depth levels of both sides.
+func (book *OrderBook) Snapshot(depth int, timeStamp time.Time) entities.OrderBookSnapshot {
+	return entities.OrderBookSnapshot{
+		SymbolPair: book.Pair,
+		Market:     book.Pair.Market,
+		TimeStamp:  timeStamp,
+		Bids:       book.Bids.Top(depth),
+		Asks:       book.Asks.Top(depth),
+	}
+}
+
+// WriteOrderBookSnapshots writes the snapshots returned by collect every
+// OrderBookSnapshotInterval until ctx is cancelled. collect should only return books
+// that are in sync with the exchange.
+func WriteOrderBookSnapshots(ctx context.Context, writer repositories.OrderBookWriter,
+	collect func(depth int, timeStamp time.Time) []entities.OrderBookSnapshot) {
+	ticker := time.NewTicker(OrderBookSnapshotInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			snapshots := collect(OrderBookDepth, now)
+			if len(snapshots) == 0 {
+				continue
+			}
+			for _, snapshot := range snapshots {
+				checkSnapshotLotSize(snapshot)
+			}
+			if err := writer.Write(snapshots); err != nil {
+				slog.Error("Error writing order book snapshots", logging.Error(err))
+			}
+		}
+	}
+}
+
+// checkSnapshotLotSize warns about the first level of the snapshot whose quantity is
+// not a whole multiple of the lot size. The snapshot is still written.
+func checkSnapshotLotSize(snapshot entities.OrderBookSnapshot) {
+	for _, levels := range [][]entities.OrderBookLevel{snapshot.Bids, snapshot.Asks} {
+		for _, level := range levels {
+			if err := snapshot.SymbolPair.CheckLotSize(level.Quantity); err != nil {
+				slog.Warn("Order book quantity off the lot size", "market", snapshot.Market.Name,
+					logging.SymbolPair(snapshot.SymbolPair), logging.Error(err))
+				return
+			}
+		}
+	}
+}
@@ -1,5 +1,21 @@
 package pollers
 
+import (
+	"DataPoller/internal/common/domain/entities"
+	"context"
+)
+
 type QuotePoller interface {
-	Poll()
+	// Poll streams quotes of the data source until ctx is cancelled, then unsubscribes
+	// and closes its connections. It returns the errors of connections that failed.
+	Poll(ctx context.Context) error
+}
+
+// SymbolPairsUpdater is implemented by quote pollers that can follow changes of the
+// pairs of their data source without a restart.
+type SymbolPairsUpdater interface {
+	// UpdateSymbolPairs subscribes the pairs that are new, unsubscribes the pairs that
+	// are gone and resubscribes the pairs whose precision or lot size changed, leaving
+	// the streams of the other pairs untouched.
+	UpdateSymbolPairs(pairs []entities.SymbolPair)
 }
@@ -0,0 +1,79 @@
+package pollers
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Writers are the repositories pollers store what they receive in. They are shared by
+// all pollers of a process.
+type Writers struct {
+	CryptoQuotes repositories.CryptoQuotesWriter
+	CryptoTrades repositories.CryptoTradesWriter
+	OrderBooks   repositories.OrderBookWriter
+	// Database is the database the writers share, if any. Close closes it after them.
+	Database io.Closer
+}
+
+// Close flushes and closes the writers, then the database they share.
+func (writers Writers) Close() error {
+	err := errors.Join(
+		writers.CryptoQuotes.Close(),
+		writers.CryptoTrades.Close(),
+		writers.OrderBooks.Close())
+	if writers.Database != nil {
+		err = errors.Join(err, writers.Database.Close())
+	}
+	return err
+}
+
+// QuotePollerConstructor creates the quote poller of one data source.
+type QuotePollerConstructor func(dataSource entities.DataSource, writers Writers) QuotePoller
+
+var (
+	constructorsMu sync.RWMutex
+	constructors   = make(map[int]QuotePollerConstructor)
+)
+
+// Register makes a quote poller constructor available for the data source id from
+// consts. It is meant to be called from the init function of the exchange package and
+// panics when the id is registered twice.
+func Register(dataSourceId int, constructor QuotePollerConstructor) {
+	constructorsMu.Lock()
+	defer constructorsMu.Unlock()
+
+	if constructor == nil {
+		panic(fmt.Sprintf("pollers: Register constructor is nil for data source %d", dataSourceId))
+	}
+	if _, dup := constructors[dataSourceId]; dup {
+		panic(fmt.Sprintf("pollers: Register called twice for data source %d", dataSourceId))
+	}
+	constructors[dataSourceId] = constructor
+}
+
+// Lookup returns the constructor registered for the data source id.
+func Lookup(dataSourceId int) (QuotePollerConstructor, bool) {
+	constructorsMu.RLock()
+	defer constructorsMu.RUnlock()
+
+	constructor, found := constructors[dataSourceId]
+	return constructor, found
+}
+
+// RegisteredIds returns the sorted ids of all data sources with a registered poller.
+func RegisteredIds() []int {
+	constructorsMu.RLock()
+	defer constructorsMu.RUnlock()
+
+	ids := make([]int, 0, len(constructors))
+	for id := range constructors {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	return ids
+}
@@ -0,0 +1,147 @@
+package pollers
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/health"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// followDelay collects the notifications of changes made in several transactions, e.g.
+// by a script adding pairs one by one, into a single reload.
+const followDelay = time.Second
+
+// SymbolPairsFollower reloads the data sources a DataSourcesWatcher reports as changed
+// and hands their new pairs to the running pollers.
+type SymbolPairsFollower struct {
+	repository repositories.DataSourcesRepository
+	mu         sync.Mutex
+	pollers    map[int]*followedPoller
+}
+
+type followedPoller struct {
+	dataSource entities.DataSource
+	poller     QuotePoller
+}
+
+func NewSymbolPairsFollower(repository repositories.DataSourcesRepository) *SymbolPairsFollower {
+	return &SymbolPairsFollower{
+		repository: repository,
+		pollers:    make(map[int]*followedPoller),
+	}
+}
+
+// Follow makes the poller, which was built for the data source, receive its changes.
+func (follower *SymbolPairsFollower) Follow(dataSource entities.DataSource, poller QuotePoller) {
+	follower.mu.Lock()
+	defer follower.mu.Unlock()
+	follower.pollers[dataSource.Id] = &followedPoller{dataSource: dataSource, poller: poller}
+}
+
+// Run applies the changes reported by the watcher until ctx is cancelled or the
+// watcher fails.
+func (follower *SymbolPairsFollower) Run(ctx context.Context, watcher repositories.DataSourcesWatcher) error {
+	changed := make(chan int)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- watcher.Watch(ctx, func(dataSourceId int) {
+			select {
+			case changed <- dataSourceId:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	pending := make(map[int]bool)
+	var apply <-chan time.Time
+
+	for {
+		select {
+		case err := <-watchErr:
+			return err
+		case dataSourceId := <-changed:
+			pending[dataSourceId] = true
+			if apply == nil {
+				apply = time.After(followDelay)
+			}
+		case <-apply:
+			follower.apply(ctx, pending)
+			pending = make(map[int]bool)
+			apply = nil
+		}
+	}
+}
+
+func (follower *SymbolPairsFollower) apply(ctx context.Context, dataSourceIds map[int]bool) {
+	follower.mu.Lock()
+	defer follower.mu.Unlock()
+
+	if dataSourceIds[repositories.AllDataSources] {
+		dataSources, err := follower.repository.FindAll(ctx)
+		if err != nil {
+			slog.Error("Error reloading data sources", logging.Error(err))
+			return
+		}
+
+		pairs := make(map[int][]entities.SymbolPair, len(dataSources))
+		for _, dataSource := range dataSources {
+			pairs[dataSource.Id] = dataSource.SymbolPairs
+		}
+		for dataSourceId, followed := range follower.pollers {
+			follower.update(followed, pairs[dataSourceId])
+		}
+		return
+	}
+
+	for dataSourceId := range dataSourceIds {
+		followed, found := follower.pollers[dataSourceId]
+		if !found {
+			slog.Info("Data source without a running poller changed, restart to poll it", "id", dataSourceId)
+			continue
+		}
+
+		dataSource, err := follower.repository.FindById(ctx, dataSourceId)
+		if err != nil {
+			slog.Error("Error reloading data source", logging.DataSourceKey, followed.dataSource.Name,
+				logging.Error(err))
+			continue
+		}
+
+		// Data sources without pairs are not found.
+		var pairs []entities.SymbolPair
+		if dataSource != nil {
+			pairs = dataSource.SymbolPairs
+		}
+		follower.update(followed, pairs)
+	}
+}
+
+func (follower *SymbolPairsFollower) update(followed *followedPoller, pairs []entities.SymbolPair) {
+	added, removed, changed := DiffSymbolPairs(followed.dataSource.SymbolPairs, pairs)
+	if len(added) == 0 && len(removed) == 0 && len(changed) == 0 {
+		return
+	}
+	logger := slog.With(logging.DataSourceKey, followed.dataSource.Name)
+
+	updater, ok := followed.poller.(SymbolPairsUpdater)
+	if !ok {
+		logger.Warn("Poller cannot change its symbol pairs while running, restart to apply",
+			"added", logging.SymbolPairs(added).Value, "removed", logging.SymbolPairs(removed).Value,
+			"changed", logging.SymbolPairs(changed).Value)
+		return
+	}
+
+	logger.Info("Updating symbol pairs",
+		"added", logging.SymbolPairs(added).Value, "removed", logging.SymbolPairs(removed).Value,
+		"changed", logging.SymbolPairs(changed).Value)
+	updater.UpdateSymbolPairs(pairs)
+	followed.dataSource.SymbolPairs = pairs
+
+	if followed.dataSource.Streams(entities.TickerChannel) {
+		health.UpdateExpectedQuotes(health.DefaultMonitor, followed.dataSource.Name, added, removed)
+	}
+}
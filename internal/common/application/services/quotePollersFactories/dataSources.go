@@ -0,0 +1,54 @@
+package quotePollersFactories
+
+import (
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/repositories/file"
+	"DataPoller/internal/common/infrastructure/repositories/postgres"
+	"DataPoller/internal/common/infrastructure/repositories/sqlite"
+	"context"
+	"database/sql"
+)
+
+// DataSources is the catalog selected by configuration.DataSources.
+type DataSources struct {
+	Repository repositories.DataSourcesRepository
+	// Watcher reports the changes of the catalog to running pollers.
+	Watcher repositories.DataSourcesWatcher
+	// database is the pool of the Postgres or SQLite catalog, nil for a catalog file.
+	database *sql.DB
+}
+
+// OpenDataSources connects to the catalog of the configuration, the main database is
+// only opened for the postgres repository.
+func OpenDataSources(ctx context.Context, configuration *infrastructure.Configuration) (*DataSources, error) {
+	switch configuration.DataSources.Repository {
+	case infrastructure.FileDataSources:
+		repository := filerepositories.NewFileDataSourcesRepository(configuration.DataSources.File)
+		return &DataSources{Repository: repository, Watcher: repository}, nil
+	case infrastructure.SqliteDataSources:
+		db, err := sqliterepositories.OpenDatabase(ctx, configuration.Sqlite)
+		if err != nil {
+			return nil, err
+		}
+		repository := sqliterepositories.NewSqliteDataSourcesRepository(db)
+		return &DataSources{Repository: repository, Watcher: repository, database: db}, nil
+	}
+
+	db, err := postgresrepositories.OpenDatabase(ctx, configuration.MainDatabase)
+	if err != nil {
+		return nil, err
+	}
+	return &DataSources{
+		Repository: postgresrepositories.NewPostgresDataSourcesRepository(db, configuration.MainDatabase.QueryTimeout),
+		Watcher:    postgresrepositories.NewPostgresDataSourcesWatcher(configuration.MainDatabase),
+		database:   db,
+	}, nil
+}
+
+func (dataSources *DataSources) Close() error {
+	if dataSources.database == nil {
+		return nil
+	}
+	return dataSources.database.Close()
+}
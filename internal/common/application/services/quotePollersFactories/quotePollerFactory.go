@@ -0,0 +1,114 @@
+package quotePollersFactories
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	_ "DataPoller/internal/common/application/services/pollers/cryptocurrencyexchanges"
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/health"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoPollerImplementation is returned for data sources without a registered poller.
+var ErrNoPollerImplementation = errors.New("no quote poller implemented")
+
+// ErrDataSourceNotFound is returned when the catalog has no data source with the id.
+var ErrDataSourceNotFound = errors.New("data source not found")
+
+type QuotePollerFactory struct {
+	dataSourcesRepository repositories.DataSourcesRepository
+	writers               pollers.Writers
+	// dataSources is the catalog opened by NewDefaultQuotePollerFactory, nil when the
+	// repository was passed in.
+	dataSources *DataSources
+	// follower receives the pollers built by a factory with its own catalog, see
+	// FollowSymbolPairs.
+	follower *pollers.SymbolPairsFollower
+}
+
+func NewQuotePollerFactory(dataSourcesRepository repositories.DataSourcesRepository,
+	writers pollers.Writers) *QuotePollerFactory {
+	return &QuotePollerFactory{
+		dataSourcesRepository: dataSourcesRepository,
+		writers:               writers,
+	}
+}
+
+// NewDefaultQuotePollerFactory wires the factory to the configured catalog and store.
+func NewDefaultQuotePollerFactory(ctx context.Context,
+	configuration *infrastructure.Configuration) (*QuotePollerFactory, error) {
+	dataSources, err := OpenDataSources(ctx, configuration)
+	if err != nil {
+		return nil, err
+	}
+	writers, err := OpenWriters(configuration)
+	if err != nil {
+		dataSources.Close()
+		return nil, err
+	}
+
+	factory := NewQuotePollerFactory(dataSources.Repository, writers)
+	factory.dataSources = dataSources
+	factory.follower = pollers.NewSymbolPairsFollower(dataSources.Repository)
+	return factory, nil
+}
+
+// FollowSymbolPairs hands the changes of the catalog to the pollers built so far until
+// ctx is cancelled or watching fails. It returns right away for a factory without its
+// own catalog, whose caller follows the changes itself.
+func (factory *QuotePollerFactory) FollowSymbolPairs(ctx context.Context) error {
+	if factory.follower == nil {
+		return nil
+	}
+	return factory.follower.Run(ctx, factory.dataSources.Watcher)
+}
+
+// Build resolves the data source by its consts id and creates its quote poller.
+func (factory *QuotePollerFactory) Build(ctx context.Context, dataSourceId int) (pollers.QuotePoller, error) {
+	if _, found := pollers.Lookup(dataSourceId); !found {
+		return nil, fmt.Errorf("data source %d: %w", dataSourceId, ErrNoPollerImplementation)
+	}
+
+	dataSource, err := factory.dataSourcesRepository.FindById(ctx, dataSourceId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load data source %d: %w", dataSourceId, err)
+	}
+	if dataSource == nil {
+		return nil, fmt.Errorf("data source %d: %w", dataSourceId, ErrDataSourceNotFound)
+	}
+
+	return factory.BuildFor(*dataSource)
+}
+
+// BuildFor creates the quote poller of an already loaded data source.
+func (factory *QuotePollerFactory) BuildFor(dataSource entities.DataSource) (pollers.QuotePoller, error) {
+	constructor, found := pollers.Lookup(dataSource.Id)
+	if !found {
+		return nil, fmt.Errorf("data source %s (%d): %w", dataSource.Name, dataSource.Id, ErrNoPollerImplementation)
+	}
+
+	// Quotes are tracked per data source, so readiness can tell which pairs went quiet.
+	writers := factory.writers
+	if dataSource.Streams(entities.TickerChannel) {
+		writers.CryptoQuotes = health.TrackQuotes(health.DefaultMonitor, dataSource, writers.CryptoQuotes)
+	}
+
+	poller := constructor(dataSource, writers)
+	if factory.follower != nil {
+		factory.follower.Follow(dataSource, poller)
+	}
+	return poller, nil
+}
+
+// Close flushes and closes the writers shared by the pollers of the factory. It must be
+// called after all of them have returned from Poll.
+func (factory *QuotePollerFactory) Close() error {
+	err := factory.writers.Close()
+	if factory.dataSources != nil {
+		err = errors.Join(err, factory.dataSources.Close())
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package quotePollersFactories
+
+import (
+	"DataPoller/internal/common/application/services/pollers"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/repositories/quest"
+	"DataPoller/internal/common/infrastructure/repositories/sqlite"
+	"context"
+)
+
+// OpenWriters creates the quotes, trades and order book writers of the store selected
+// by configuration.TimeSeriesDatabase. Closing them is up to the caller.
+func OpenWriters(configuration *infrastructure.Configuration) (pollers.Writers, error) {
+	if configuration.TimeSeriesDatabase.Store == infrastructure.SqliteStore {
+		return openSqliteWriters(configuration.Sqlite, sqliterepositories.DefaultSqliteWriterOptions)
+	}
+	return openQuestWriters(configuration.TimeSeriesDatabase, questrepositories.DefaultQuestWriterOptions)
+}
+
+func openQuestWriters(config infrastructure.TimeSeriesDatabaseConfiguration,
+	options questrepositories.QuestWriterOptions) (pollers.Writers, error) {
+	questCryptoQuotesWriter, err := questrepositories.NewQuestCryptoQuotesWriter(config, options)
+	if err != nil {
+		return pollers.Writers{}, err
+	}
+	questCryptoTradesWriter, err := questrepositories.NewQuestCryptoTradesWriter(config, options)
+	if err != nil {
+		questCryptoQuotesWriter.Close()
+		return pollers.Writers{}, err
+	}
+	questOrderBookWriter, err := questrepositories.NewQuestOrderBookWriter(config, options)
+	if err != nil {
+		questCryptoQuotesWriter.Close()
+		questCryptoTradesWriter.Close()
+		return pollers.Writers{}, err
+	}
+
+	return pollers.Writers{
+		CryptoQuotes: questCryptoQuotesWriter,
+		CryptoTrades: questCryptoTradesWriter,
+		OrderBooks:   questOrderBookWriter,
+	}, nil
+}
+
+func openSqliteWriters(config infrastructure.SqliteConfiguration,
+	options sqliterepositories.SqliteWriterOptions) (pollers.Writers, error) {
+	db, err := sqliterepositories.OpenWriterDatabase(context.Background(), config)
+	if err != nil {
+		return pollers.Writers{}, err
+	}
+
+	return pollers.Writers{
+		CryptoQuotes: sqliterepositories.NewSqliteCryptoQuotesWriter(db, options),
+		CryptoTrades: sqliterepositories.NewSqliteCryptoTradesWriter(db, options),
+		OrderBooks:   sqliterepositories.NewSqliteOrderBookWriter(db, options),
+		Database:     db,
+	}, nil
+}
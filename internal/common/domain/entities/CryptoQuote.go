@@ -1,15 +1,69 @@
 package entities
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type CryptoQuote struct {
 	SymbolPair SymbolPair
 	Market     Market
-	TimeStamp  time.Time
-	Rate       uint64
-	OpenRate   uint64
-	HighRate   uint64
-	LowRate    uint64
-	CloseRate  uint64
-	Volume     uint64
+	// TimeStamp is when the exchange produced the update, or ReceivedAt for updates the
+	// exchange does not timestamp.
+	TimeStamp time.Time
+	Rate      Decimal
+	OpenRate  Decimal
+	HighRate  Decimal
+	LowRate   Decimal
+	CloseRate Decimal
+	Volume    Decimal
+	// QuoteVolume is the 24h volume in the quote symbol, stored with the quantity
+	// precision like Volume.
+	QuoteVolume Decimal
+	BidRate     Decimal
+	BidSize     Decimal
+	AskRate     Decimal
+	AskSize     Decimal
+	// ReceivedAt is when the message carrying the quote was read from the connection.
+	ReceivedAt time.Time
+}
+
+// ScaledQuote holds the unscaled values of a quote as stored in crypto_quotes: rates
+// at the price precision of the symbol pair, volumes and sizes at its quantity
+// precision. A stored Rate means Rate * 10^-PriceScale.
+type ScaledQuote struct {
+	Rate, OpenRate, HighRate, LowRate, CloseRate, BidRate, AskRate int64
+	Volume, QuoteVolume, BidSize, AskSize                          int64
+}
+
+// Scaled rescales the values of the quote to the precisions of its symbol pair. It
+// fails for values that do not fit at that precision.
+func (quote CryptoQuote) Scaled() (ScaledQuote, error) {
+	var errs []error
+	rescale := func(value Decimal, scale int32) int64 {
+		rescaled, err := value.Rescale(scale)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		return rescaled.Unscaled
+	}
+
+	prices := quote.SymbolPair.PricePrecision
+	quantities := quote.SymbolPair.QuantityPrecision
+
+	scaled := ScaledQuote{
+		Rate:        rescale(quote.Rate, prices),
+		OpenRate:    rescale(quote.OpenRate, prices),
+		HighRate:    rescale(quote.HighRate, prices),
+		LowRate:     rescale(quote.LowRate, prices),
+		CloseRate:   rescale(quote.CloseRate, prices),
+		BidRate:     rescale(quote.BidRate, prices),
+		AskRate:     rescale(quote.AskRate, prices),
+		Volume:      rescale(quote.Volume, quantities),
+		QuoteVolume: rescale(quote.QuoteVolume, quantities),
+		BidSize:     rescale(quote.BidSize, quantities),
+		AskSize:     rescale(quote.AskSize, quantities),
+	}
+
+	return scaled, errors.Join(errs...)
 }
@@ -0,0 +1,24 @@
+package entities
+
+import "time"
+
+// TradeSide is the side of the taker of a trade.
+type TradeSide string
+
+const (
+	BuySide  TradeSide = "buy"
+	SellSide TradeSide = "sell"
+)
+
+type CryptoTrade struct {
+	SymbolPair SymbolPair
+	Market     Market
+	// TradeId is the exchange's id of the trade, for Binance aggregated trades the
+	// aggregate trade id.
+	TradeId  int64
+	Price    Decimal
+	Quantity Decimal
+	Side     TradeSide
+	// TimeStamp is when the exchange executed the trade.
+	TimeStamp time.Time
+}
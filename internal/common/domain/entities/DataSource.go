@@ -1,5 +1,17 @@
 package entities
 
+import "slices"
+
+// Channels a data source can stream, see DataSource.Channels.
+const (
+	TickerChannel = "ticker"
+	TradesChannel = "trades"
+	// AggregatedTradesChannel streams Binance aggregated trades instead of single trades.
+	AggregatedTradesChannel = "aggTrades"
+	// OrderBookChannel maintains level 2 order books and writes their top periodically.
+	OrderBookChannel = "book"
+)
+
 type DataSource struct {
 	Id               int
 	Name             string
@@ -8,4 +20,15 @@ type DataSource struct {
 	Password         string
 	RateLimit        int
 	SymbolPairs      []SymbolPair
+	// Channels selects what the poller streams for every symbol pair. Empty streams
+	// tickers only.
+	Channels []string
+}
+
+// Streams reports whether the poller of the data source should stream the channel.
+func (dataSource DataSource) Streams(channel string) bool {
+	if len(dataSource.Channels) == 0 {
+		return channel == TickerChannel
+	}
+	return slices.Contains(dataSource.Channels, channel)
 }
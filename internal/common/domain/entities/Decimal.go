@@ -0,0 +1,235 @@
+package entities
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number with the value Unscaled * 10^-Scale. Exchange
+// prices are parsed from their string form into it, so no digit is lost to float64.
+type Decimal struct {
+	Unscaled int64
+	Scale    int32
+}
+
+var bigTen = big.NewInt(10)
+
+// maxDecimalExponent bounds the exponent ParseDecimal accepts. Larger ones cannot fit
+// into 18 significant digits anyway and would make it build huge powers of ten.
+const maxDecimalExponent = 36
+
+// ParseDecimal parses a decimal string such as "0.00001234", "-12.5" or "1.5e-08".
+// It fails when the value does not fit into 18 significant digits or its exponent is
+// beyond ±maxDecimalExponent.
+func ParseDecimal(value string) (Decimal, error) {
+	text := strings.TrimSpace(value)
+	if text == "" {
+		return Decimal{}, fmt.Errorf("empty decimal")
+	}
+
+	exponent := 0
+	if i := strings.IndexAny(text, "eE"); i >= 0 {
+		parsed, err := strconv.Atoi(text[i+1:])
+		if err != nil {
+			return Decimal{}, fmt.Errorf("invalid decimal %q", value)
+		}
+		if parsed < -maxDecimalExponent || parsed > maxDecimalExponent {
+			return Decimal{}, fmt.Errorf("decimal %q out of range", value)
+		}
+		exponent = parsed
+		text = text[:i]
+	}
+
+	negative := false
+	switch {
+	case strings.HasPrefix(text, "-"):
+		negative = true
+		text = text[1:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+
+	integer, fraction, _ := strings.Cut(text, ".")
+	digits := integer + fraction
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal %q", value)
+	}
+
+	unscaled, _ := new(big.Int).SetString(digits, 10)
+	if negative {
+		unscaled.Neg(unscaled)
+	}
+
+	scale := len(fraction) - exponent
+	if scale < 0 {
+		unscaled.Mul(unscaled, new(big.Int).Exp(bigTen, big.NewInt(int64(-scale)), nil))
+		scale = 0
+	}
+
+	return newDecimal(unscaled, scale, value)
+}
+
+// newDecimal strips trailing zeros until the unscaled value fits into an int64.
+func newDecimal(unscaled *big.Int, scale int, value string) (Decimal, error) {
+	quotient, remainder := new(big.Int), new(big.Int)
+	for !unscaled.IsInt64() && scale > 0 {
+		quotient.QuoRem(unscaled, bigTen, remainder)
+		if remainder.Sign() != 0 {
+			break
+		}
+		unscaled.Set(quotient)
+		scale--
+	}
+
+	if !unscaled.IsInt64() {
+		return Decimal{}, fmt.Errorf("decimal %s out of range", value)
+	}
+
+	return Decimal{Unscaled: unscaled.Int64(), Scale: int32(scale)}, nil
+}
+
+// Rescale returns the value with the given number of decimals, rounding half away from
+// zero when digits have to be dropped.
+func (decimal Decimal) Rescale(scale int32) (Decimal, error) {
+	if scale == decimal.Scale {
+		return decimal, nil
+	}
+
+	unscaled := big.NewInt(decimal.Unscaled)
+
+	if scale > decimal.Scale {
+		factor := new(big.Int).Exp(bigTen, big.NewInt(int64(scale-decimal.Scale)), nil)
+		unscaled.Mul(unscaled, factor)
+	} else {
+		factor := new(big.Int).Exp(bigTen, big.NewInt(int64(decimal.Scale-scale)), nil)
+		remainder := new(big.Int)
+		unscaled.QuoRem(unscaled, factor, remainder)
+
+		// Round half away from zero: compare twice the remainder with the factor.
+		remainder.Abs(remainder).Lsh(remainder, 1)
+		if remainder.Cmp(factor) >= 0 {
+			if decimal.Unscaled < 0 {
+				unscaled.Sub(unscaled, big.NewInt(1))
+			} else {
+				unscaled.Add(unscaled, big.NewInt(1))
+			}
+		}
+	}
+
+	if !unscaled.IsInt64() {
+		return Decimal{}, fmt.Errorf("decimal %s out of range at scale %d", decimal, scale)
+	}
+
+	return Decimal{Unscaled: unscaled.Int64(), Scale: scale}, nil
+}
+
+// Sub returns decimal - other at the larger scale of both.
+func (decimal Decimal) Sub(other Decimal) (Decimal, error) {
+	scale := decimal.Scale
+	if other.Scale > scale {
+		scale = other.Scale
+	}
+
+	left, err := decimal.Rescale(scale)
+	if err != nil {
+		return Decimal{}, err
+	}
+	right, err := other.Rescale(scale)
+	if err != nil {
+		return Decimal{}, err
+	}
+
+	difference := new(big.Int).Sub(big.NewInt(left.Unscaled), big.NewInt(right.Unscaled))
+	return newDecimal(difference, int(scale), difference.String())
+}
+
+// IsMultipleOf reports whether the value is a whole multiple of step. Every value is a
+// multiple of a zero step.
+func (decimal Decimal) IsMultipleOf(step Decimal) bool {
+	if step.IsZero() {
+		return true
+	}
+
+	scale := max(decimal.Scale, step.Scale, 0)
+	value := new(big.Int).Mul(big.NewInt(decimal.Unscaled),
+		new(big.Int).Exp(bigTen, big.NewInt(int64(scale-decimal.Scale)), nil))
+	multiple := new(big.Int).Mul(big.NewInt(step.Unscaled),
+		new(big.Int).Exp(bigTen, big.NewInt(int64(scale-step.Scale)), nil))
+	return new(big.Int).Rem(value, multiple).Sign() == 0
+}
+
+func (decimal Decimal) IsZero() bool {
+	return decimal.Unscaled == 0
+}
+
+func (decimal Decimal) String() string {
+	text := big.NewInt(decimal.Unscaled).String()
+	if decimal.Scale <= 0 {
+		return text + strings.Repeat("0", int(-decimal.Scale))
+	}
+
+	negative := strings.HasPrefix(text, "-")
+	text = strings.TrimPrefix(text, "-")
+	if len(text) <= int(decimal.Scale) {
+		text = strings.Repeat("0", int(decimal.Scale)-len(text)+1) + text
+	}
+
+	point := len(text) - int(decimal.Scale)
+	text = text[:point] + "." + text[point:]
+	if negative {
+		text = "-" + text
+	}
+	return text
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of the value.
+func (decimal Decimal) Sign() int {
+	switch {
+	case decimal.Unscaled < 0:
+		return -1
+	case decimal.Unscaled > 0:
+		return 1
+	}
+	return 0
+}
+
+// Abs returns the absolute value.
+func (decimal Decimal) Abs() Decimal {
+	if decimal.Unscaled < 0 {
+		return decimal.Neg()
+	}
+	return decimal
+}
+
+// Neg returns -decimal.
+func (decimal Decimal) Neg() Decimal {
+	if decimal.Unscaled != math.MinInt64 {
+		decimal.Unscaled = -decimal.Unscaled
+	}
+	return decimal
+}
+
+// Normalize strips trailing zeros, so equal values have equal representations and can
+// be used as map keys.
+func (decimal Decimal) Normalize() Decimal {
+	if decimal.Unscaled == 0 {
+		return Decimal{}
+	}
+	for decimal.Scale > 0 && decimal.Unscaled%10 == 0 {
+		decimal.Unscaled /= 10
+		decimal.Scale--
+	}
+	return decimal
+}
+
+// Cmp returns -1, 0 or 1 when decimal is less than, equal to or greater than other.
+func (decimal Decimal) Cmp(other Decimal) int {
+	left := new(big.Int).Mul(big.NewInt(decimal.Unscaled),
+		new(big.Int).Exp(bigTen, big.NewInt(int64(max(other.Scale-decimal.Scale, 0))), nil))
+	right := new(big.Int).Mul(big.NewInt(other.Unscaled),
+		new(big.Int).Exp(bigTen, big.NewInt(int64(max(decimal.Scale-other.Scale, 0))), nil))
+	return left.Cmp(right)
+}
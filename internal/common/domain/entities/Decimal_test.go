@@ -0,0 +1,162 @@
+package entities
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Decimal
+		wantErr bool
+	}{
+		{value: "0", want: Decimal{Unscaled: 0, Scale: 0}},
+		{value: "12.5", want: Decimal{Unscaled: 125, Scale: 1}},
+		{value: "-12.5", want: Decimal{Unscaled: -125, Scale: 1}},
+		{value: "+12.5", want: Decimal{Unscaled: 125, Scale: 1}},
+		{value: " 0.00001234 ", want: Decimal{Unscaled: 1234, Scale: 8}},
+		{value: ".5", want: Decimal{Unscaled: 5, Scale: 1}},
+		{value: "5.", want: Decimal{Unscaled: 5, Scale: 0}},
+		{value: "1.5e-08", want: Decimal{Unscaled: 15, Scale: 9}},
+		{value: "1.5E3", want: Decimal{Unscaled: 1500, Scale: 0}},
+		{value: "9223372036854775807", want: Decimal{Unscaled: math.MaxInt64, Scale: 0}},
+		// Trailing zeros are only dropped until the value fits.
+		{value: "1.00000000000000000000", want: Decimal{Unscaled: 1000000000000000000, Scale: 18}},
+		{value: "9223372036854775808", wantErr: true},
+		{value: "1.0000000000000000001", wantErr: true},
+		{value: "1e37", wantErr: true},
+		{value: "1e-37", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "-", wantErr: true},
+		{value: ".", wantErr: true},
+		{value: "1.2.3", wantErr: true},
+		{value: "1e", wantErr: true},
+		{value: "abc", wantErr: true},
+		{value: "NaN", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := ParseDecimal(test.value)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("ParseDecimal(%q) = %v, want an error", test.value, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseDecimal(%q) failed: %v", test.value, err)
+			}
+			if got != test.want {
+				t.Errorf("ParseDecimal(%q) = %#v, want %#v", test.value, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecimalRescale(t *testing.T) {
+	tests := []struct {
+		name    string
+		decimal Decimal
+		scale   int32
+		want    Decimal
+		wantErr bool
+	}{
+		{name: "same scale", decimal: Decimal{Unscaled: 125, Scale: 1}, scale: 1, want: Decimal{Unscaled: 125, Scale: 1}},
+		{name: "up", decimal: Decimal{Unscaled: 125, Scale: 1}, scale: 3, want: Decimal{Unscaled: 12500, Scale: 3}},
+		{name: "down exact", decimal: Decimal{Unscaled: 12500, Scale: 3}, scale: 1, want: Decimal{Unscaled: 125, Scale: 1}},
+		{name: "down below half", decimal: Decimal{Unscaled: 1249, Scale: 3}, scale: 2, want: Decimal{Unscaled: 125, Scale: 2}},
+		{name: "half away from zero", decimal: Decimal{Unscaled: 125, Scale: 2}, scale: 1, want: Decimal{Unscaled: 13, Scale: 1}},
+		{name: "negative half away from zero", decimal: Decimal{Unscaled: -125, Scale: 2}, scale: 1, want: Decimal{Unscaled: -13, Scale: 1}},
+		{name: "below half", decimal: Decimal{Unscaled: 124, Scale: 2}, scale: 1, want: Decimal{Unscaled: 12, Scale: 1}},
+		{name: "negative below half", decimal: Decimal{Unscaled: -124, Scale: 2}, scale: 1, want: Decimal{Unscaled: -12, Scale: 1}},
+		{name: "to zero", decimal: Decimal{Unscaled: 4, Scale: 9}, scale: 8, want: Decimal{Unscaled: 0, Scale: 8}},
+		{name: "half to unit", decimal: Decimal{Unscaled: 5, Scale: 9}, scale: 8, want: Decimal{Unscaled: 1, Scale: 8}},
+		{name: "rounding up at max", decimal: Decimal{Unscaled: math.MaxInt64, Scale: 1}, scale: 0, want: Decimal{Unscaled: 922337203685477581, Scale: 0}},
+		{name: "overflow up", decimal: Decimal{Unscaled: math.MaxInt64 / 10, Scale: 0}, scale: 2, wantErr: true},
+		{name: "overflow negative", decimal: Decimal{Unscaled: math.MinInt64 / 10, Scale: 0}, scale: 2, wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := test.decimal.Rescale(test.scale)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("%v.Rescale(%d) = %#v, want an error", test.decimal, test.scale, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("%v.Rescale(%d) failed: %v", test.decimal, test.scale, err)
+			}
+			if got != test.want {
+				t.Errorf("%v.Rescale(%d) = %#v, want %#v", test.decimal, test.scale, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecimalIsMultipleOf(t *testing.T) {
+	tests := []struct {
+		decimal string
+		step    string
+		want    bool
+	}{
+		{decimal: "1.5", step: "0.5", want: true},
+		{decimal: "1.50000", step: "0.5", want: true},
+		{decimal: "1.5", step: "0.50000", want: true},
+		{decimal: "1.6", step: "0.5", want: false},
+		{decimal: "-1.5", step: "0.5", want: true},
+		{decimal: "0", step: "0.001", want: true},
+		{decimal: "0.0001", step: "0.001", want: false},
+		{decimal: "1500", step: "1e2", want: true},
+		{decimal: "1550", step: "1e2", want: false},
+		{decimal: "1.23456789", step: "0", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.decimal+"/"+test.step, func(t *testing.T) {
+			decimal, step := mustParseDecimal(t, test.decimal), mustParseDecimal(t, test.step)
+			if got := decimal.IsMultipleOf(step); got != test.want {
+				t.Errorf("%s.IsMultipleOf(%s) = %v, want %v", test.decimal, test.step, got, test.want)
+			}
+		})
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	tests := []struct {
+		left  string
+		right string
+		want  int
+	}{
+		{left: "1", right: "1", want: 0},
+		{left: "1.0", right: "1.000", want: 0},
+		{left: "1.01", right: "1.1", want: -1},
+		{left: "1.1", right: "1.01", want: 1},
+		{left: "-1.1", right: "-1.01", want: -1},
+		{left: "-1", right: "0", want: -1},
+		{left: "0", right: "0.00", want: 0},
+		{left: "9223372036854775807", right: "0.000000000000000001", want: 1},
+		{left: "0.000000000000000001", right: "0.000000000000000002", want: -1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.left+"/"+test.right, func(t *testing.T) {
+			left, right := mustParseDecimal(t, test.left), mustParseDecimal(t, test.right)
+			if got := left.Cmp(right); got != test.want {
+				t.Errorf("%s.Cmp(%s) = %d, want %d", test.left, test.right, got, test.want)
+			}
+		})
+	}
+}
+
+func mustParseDecimal(t *testing.T, value string) Decimal {
+	t.Helper()
+	decimal, err := ParseDecimal(value)
+	if err != nil {
+		t.Fatalf("ParseDecimal(%q) failed: %v", value, err)
+	}
+	return decimal
+}
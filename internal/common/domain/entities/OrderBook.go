@@ -0,0 +1,18 @@
+package entities
+
+import "time"
+
+type OrderBookLevel struct {
+	Price    Decimal
+	Quantity Decimal
+}
+
+// OrderBookSnapshot is the top of an order book at one point in time, best levels
+// first.
+type OrderBookSnapshot struct {
+	SymbolPair SymbolPair
+	Market     Market
+	TimeStamp  time.Time
+	Bids       []OrderBookLevel
+	Asks       []OrderBookLevel
+}
@@ -1,8 +1,71 @@
 package entities
 
+import "fmt"
+
+// DefaultPricePrecision and DefaultQuantityPrecision are used for pairs whose
+// precision is not configured.
+const (
+	DefaultPricePrecision    int32 = 8
+	DefaultQuantityPrecision int32 = 8
+)
+
 type SymbolPair struct {
 	Id          int
 	BaseSymbol  Symbol
 	QuoteSymbol Symbol
 	Market      Market
+	// PricePrecision is the number of decimals rates of the pair are stored with.
+	PricePrecision int32
+	// QuantityPrecision is the number of decimals volumes and sizes are stored with.
+	QuantityPrecision int32
+	// LotSize is the smallest quantity step the exchange accepts, zero when unknown.
+	LotSize Decimal
+}
+
+// ParsePrice parses an exchange price and rounds it to the pair's price precision.
+func (pair SymbolPair) ParsePrice(value string) (Decimal, error) {
+	price, err := ParseDecimal(value)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return price.Rescale(pair.PricePrecision)
+}
+
+// ParseQuantity parses an exchange volume or size and rounds it to the pair's quantity
+// precision.
+func (pair SymbolPair) ParseQuantity(value string) (Decimal, error) {
+	quantity, err := ParseDecimal(value)
+	if err != nil {
+		return Decimal{}, err
+	}
+	return quantity.Rescale(pair.QuantityPrecision)
+}
+
+// CheckLotSize fails for a trade or order book quantity that is not a whole multiple of
+// the lot size, which means the lot size of the pair is out of date. The quantity is
+// checked at the pair's quantity precision, so float noise of exchanges that send
+// numbers does not count.
+func (pair SymbolPair) CheckLotSize(quantity Decimal) error {
+	rounded, err := quantity.Rescale(pair.QuantityPrecision)
+	if err != nil {
+		return err
+	}
+	if rounded.IsMultipleOf(pair.LotSize) {
+		return nil
+	}
+	return fmt.Errorf("quantity %s is not a multiple of the lot size %s", quantity, pair.LotSize)
+}
+
+// ScaledLotSize returns the lot size unscaled at the pair's quantity precision, as
+// stored next to QuantityScale. It reports false when the lot size is unknown or finer
+// than the precision.
+func (pair SymbolPair) ScaledLotSize() (int64, bool) {
+	if pair.LotSize.Sign() <= 0 {
+		return 0, false
+	}
+	lotSize, err := pair.LotSize.Rescale(pair.QuantityPrecision)
+	if err != nil || lotSize.IsZero() {
+		return 0, false
+	}
+	return lotSize.Unscaled, true
 }
@@ -6,4 +6,6 @@ import (
 
 type CryptoQuotesWriter interface {
 	Write(quotes []entities.CryptoQuote) error
+	// Close flushes quotes that are still pending and releases the writer.
+	Close() error
 }
@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+)
+
+type CryptoTradesWriter interface {
+	Write(trades []entities.CryptoTrade) error
+	// Close flushes trades that are still pending and releases the writer.
+	Close() error
+}
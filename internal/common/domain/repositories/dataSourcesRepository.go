@@ -2,9 +2,10 @@ package repositories
 
 import (
 	"DataPoller/internal/common/domain/entities"
+	"context"
 )
 
 type DataSourcesRepository interface {
-	FindAll() ([]*entities.DataSource, error)
-	FindById(id int) (*entities.DataSource, error)
+	FindAll(ctx context.Context) ([]*entities.DataSource, error)
+	FindById(ctx context.Context, id int) (*entities.DataSource, error)
 }
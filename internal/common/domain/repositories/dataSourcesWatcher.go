@@ -0,0 +1,13 @@
+package repositories
+
+import "context"
+
+// AllDataSources is passed to the callback of DataSourcesWatcher.Watch when any data
+// source may have changed, e.g. after notifications were missed.
+const AllDataSources = 0
+
+type DataSourcesWatcher interface {
+	// Watch calls changed with the id of every data source whose symbol pairs changed
+	// until ctx is cancelled.
+	Watch(ctx context.Context, changed func(dataSourceId int)) error
+}
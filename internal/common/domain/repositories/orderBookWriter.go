@@ -0,0 +1,11 @@
+package repositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+)
+
+type OrderBookWriter interface {
+	Write(snapshots []entities.OrderBookSnapshot) error
+	// Close flushes snapshots that are still pending and releases the writer.
+	Close() error
+}
@@ -1,30 +1,184 @@
 package infrastructure
 
 import (
+	"DataPoller/internal/common/infrastructure/logging"
+	"errors"
+	"flag"
 	"fmt"
 	"gopkg.in/yaml.v3"
-	_ "gopkg.in/yaml.v3"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
 )
 
+const (
+	// DefaultConfigPath is read when neither --config nor DATAPOLLER_CONFIG is set.
+	DefaultConfigPath = "configs/config.yml"
+	// ConfigPathEnv names the environment variable holding the configuration path.
+	ConfigPathEnv = "DATAPOLLER_CONFIG"
+	// EnvPrefix prefixes the environment variables overriding configuration fields,
+	// e.g. DATAPOLLER_MAIN_DATABASE_PASSWORD overrides main_database.password.
+	EnvPrefix = "DATAPOLLER"
+)
+
+// Repositories the data sources can be read from, see DataSourcesConfiguration.
+const (
+	PostgresDataSources = "postgres"
+	FileDataSources     = "file"
+	SqliteDataSources   = "sqlite"
+)
+
+// Stores quotes, trades and order books can be written to, see
+// TimeSeriesDatabaseConfiguration.Store.
+const (
+	QuestStore  = "questdb"
+	SqliteStore = "sqlite"
+)
+
+type MainDatabaseConfiguration struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Database string `yaml:"database"`
+	// SslMode is the libpq sslmode: disable, require, verify-ca or verify-full.
+	SslMode string `yaml:"sslmode"`
+	// SslRootCert is the CA certificate file checked by verify-ca and verify-full.
+	SslRootCert           string        `yaml:"sslrootcert"`
+	MaxOpenConnections    int           `yaml:"max_open_connections"`
+	MaxIdleConnections    int           `yaml:"max_idle_connections"`
+	ConnectionMaxLifetime time.Duration `yaml:"connection_max_lifetime"`
+	ConnectTimeout        time.Duration `yaml:"connect_timeout"`
+	QueryTimeout          time.Duration `yaml:"query_timeout"`
+}
+
+type TimeSeriesDatabaseConfiguration struct {
+	// Store is questdb, configured by the other fields, or sqlite, which writes to the
+	// database of SqliteConfiguration.
+	Store string `yaml:"store"`
+	Host  string `yaml:"host"`
+	// Port is the line protocol port rows are written to.
+	Port string `yaml:"port"`
+	// PgPort is the PostgreSQL wire protocol port migrations are run on, with Username,
+	// Password and Database.
+	PgPort   string `yaml:"pg_port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Database string `yaml:"database"`
+}
+
+// Address is the host:port of the QuestDB line protocol endpoint.
+func (config TimeSeriesDatabaseConfiguration) Address() string {
+	return config.Host + ":" + config.Port
+}
+
+// DataSourcesConfiguration selects where the data sources and their symbol pairs are
+// read from.
+type DataSourcesConfiguration struct {
+	// Repository is postgres for the tds catalog of the main database, file for a
+	// catalog file or sqlite for the catalog in the database of SqliteConfiguration.
+	// Only postgres needs the main database.
+	Repository string `yaml:"repository"`
+	// File is the YAML or JSON catalog read by the file repository.
+	File string `yaml:"file"`
+}
+
+// SqliteConfiguration is the database file of the self-contained mode, which holds the
+// catalog for the sqlite data sources repository and the rows of the sqlite store.
+type SqliteConfiguration struct {
+	Path string `yaml:"path"`
+	// BusyTimeout is how long a connection waits for the lock of another one.
+	BusyTimeout time.Duration `yaml:"busy_timeout"`
+}
+
 type Configuration struct {
-	MainDatabase struct {
-		Host     string `yaml:"host"`
-		Port     string `yaml:"port"`
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-		Database string `yaml:"database"`
-	} `yaml:"main_database"`
-	TimeSeriesDatabase struct {
-		Host     string `yaml:"host"`
-		Port     string `yaml:"port"`
-		Username string `yaml:"username"`
-		Password string `yaml:"password"`
-	} `yaml:"time_series_database"`
-}
-
-func (configuration *Configuration) LoadFromFile() error {
-	file, err := os.Open("configs/config.yml")
+	MainDatabase       MainDatabaseConfiguration       `yaml:"main_database"`
+	TimeSeriesDatabase TimeSeriesDatabaseConfiguration `yaml:"time_series_database"`
+	DataSources        DataSourcesConfiguration        `yaml:"data_sources"`
+	Sqlite             SqliteConfiguration             `yaml:"sqlite"`
+	Logging            logging.Options                 `yaml:"logging"`
+}
+
+// DefaultConfiguration holds the values of the fields missing from the file and the
+// environment. Credentials have no defaults.
+func DefaultConfiguration() Configuration {
+	return Configuration{
+		MainDatabase: MainDatabaseConfiguration{
+			Host:                  "localhost",
+			Port:                  "5432",
+			Database:              "td",
+			SslMode:               "disable",
+			MaxOpenConnections:    10,
+			MaxIdleConnections:    2,
+			ConnectionMaxLifetime: 30 * time.Minute,
+			ConnectTimeout:        5 * time.Second,
+			QueryTimeout:          30 * time.Second,
+		},
+		TimeSeriesDatabase: TimeSeriesDatabaseConfiguration{
+			Store:    QuestStore,
+			Host:     "localhost",
+			Port:     "9009",
+			PgPort:   "8812",
+			Database: "qdb",
+		},
+		DataSources: DataSourcesConfiguration{
+			Repository: PostgresDataSources,
+		},
+		Sqlite: SqliteConfiguration{
+			Path:        "datapoller.db",
+			BusyTimeout: 5 * time.Second,
+		},
+		Logging: logging.DefaultOptions,
+	}
+}
+
+// ConfigPathFlag registers the --config flag on the command line flag set.
+func ConfigPathFlag() *string {
+	return ConfigPathFlagOn(flag.CommandLine)
+}
+
+// ConfigPathFlagOn registers the --config flag on the flag set of a subcommand.
+func ConfigPathFlagOn(flags *flag.FlagSet) *string {
+	return flags.String("config", "",
+		"path of the configuration file, defaults to $"+ConfigPathEnv+" or "+DefaultConfigPath)
+}
+
+// LoadConfiguration reads the configuration once at startup. The file at path, or at
+// DATAPOLLER_CONFIG when path is empty, is laid over DefaultConfiguration, then every
+// field can be overridden from the environment. Only the default path may be missing,
+// in which case the configuration comes from the defaults and the environment alone.
+func LoadConfiguration(path string) (*Configuration, error) {
+	if path == "" {
+		path = os.Getenv(ConfigPathEnv)
+	}
+	optional := path == ""
+	if optional {
+		path = DefaultConfigPath
+	}
+
+	configuration := DefaultConfiguration()
+
+	if err := configuration.loadFromFile(path); err != nil {
+		if !optional || !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	if err := applyEnvOverrides(reflect.ValueOf(&configuration).Elem(), EnvPrefix); err != nil {
+		return nil, err
+	}
+
+	if err := configuration.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &configuration, nil
+}
+
+func (configuration *Configuration) loadFromFile(path string) error {
+	file, err := os.Open(path)
 	if err != nil {
 		return fmt.Errorf("failed to open file: %w", err)
 	}
@@ -32,10 +186,145 @@ func (configuration *Configuration) LoadFromFile() error {
 	defer file.Close()
 
 	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
 	err = decoder.Decode(configuration)
 	if err != nil {
-		return fmt.Errorf("failed to parse file: %w", err)
+		return fmt.Errorf("failed to parse file %s: %w", path, err)
 	}
 
 	return nil
 }
+
+// applyEnvOverrides sets every field of the struct for which an environment variable
+// named after its prefix and yaml key is set.
+func applyEnvOverrides(value reflect.Value, prefix string) error {
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Field(i)
+		key, _, _ := strings.Cut(value.Type().Field(i).Tag.Get("yaml"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+
+		if field.Kind() == reflect.Struct {
+			if err := applyEnvOverrides(field, name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		raw, found := os.LookupEnv(name)
+		if !found {
+			continue
+		}
+		if err := setField(field, raw); err != nil {
+			return fmt.Errorf("invalid value of %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, raw string) error {
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(duration))
+		return nil
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(parsed)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(parsed)
+	default:
+		return fmt.Errorf("unsupported field type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate reports every invalid field, named by its yaml path.
+func (configuration *Configuration) Validate() error {
+	var errs []error
+	required := func(name string, value string) {
+		if value == "" {
+			errs = append(errs, fmt.Errorf("%s is required", name))
+		}
+	}
+	port := func(name string, value string) {
+		if value == "" {
+			return
+		}
+		if number, err := strconv.Atoi(value); err != nil || number < 1 || number > 65535 {
+			errs = append(errs, fmt.Errorf("%s must be a port number, got %q", name, value))
+		}
+	}
+
+	switch configuration.DataSources.Repository {
+	case PostgresDataSources:
+		// The main database is only needed for the catalog.
+		required("main_database.host", configuration.MainDatabase.Host)
+		required("main_database.port", configuration.MainDatabase.Port)
+		required("main_database.username", configuration.MainDatabase.Username)
+		required("main_database.database", configuration.MainDatabase.Database)
+	case FileDataSources:
+		required("data_sources.file", configuration.DataSources.File)
+	case SqliteDataSources:
+	default:
+		errs = append(errs, fmt.Errorf("data_sources.repository must be %s, %s or %s, got %q",
+			PostgresDataSources, FileDataSources, SqliteDataSources, configuration.DataSources.Repository))
+	}
+
+	port("main_database.port", configuration.MainDatabase.Port)
+	switch configuration.MainDatabase.SslMode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		errs = append(errs, fmt.Errorf("main_database.sslmode must be disable, require, verify-ca or verify-full, got %q",
+			configuration.MainDatabase.SslMode))
+	}
+	if configuration.MainDatabase.MaxOpenConnections < 0 || configuration.MainDatabase.MaxIdleConnections < 0 {
+		errs = append(errs, errors.New("main_database connection limits must not be negative"))
+	}
+	if configuration.MainDatabase.ConnectionMaxLifetime < 0 || configuration.MainDatabase.ConnectTimeout < 0 ||
+		configuration.MainDatabase.QueryTimeout < 0 {
+		errs = append(errs, errors.New("main_database timeouts must not be negative"))
+	}
+
+	switch configuration.TimeSeriesDatabase.Store {
+	case QuestStore:
+		required("time_series_database.host", configuration.TimeSeriesDatabase.Host)
+		required("time_series_database.port", configuration.TimeSeriesDatabase.Port)
+	case SqliteStore:
+	default:
+		errs = append(errs, fmt.Errorf("time_series_database.store must be %s or %s, got %q",
+			QuestStore, SqliteStore, configuration.TimeSeriesDatabase.Store))
+	}
+	port("time_series_database.port", configuration.TimeSeriesDatabase.Port)
+	port("time_series_database.pg_port", configuration.TimeSeriesDatabase.PgPort)
+
+	if configuration.DataSources.Repository == SqliteDataSources ||
+		configuration.TimeSeriesDatabase.Store == SqliteStore {
+		required("sqlite.path", configuration.Sqlite.Path)
+	}
+	if configuration.Sqlite.BusyTimeout < 0 {
+		errs = append(errs, errors.New("sqlite.busy_timeout must not be negative"))
+	}
+
+	if err := configuration.Logging.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("logging: %w", err))
+	}
+
+	return errors.Join(errs...)
+}
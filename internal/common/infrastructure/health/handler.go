@@ -0,0 +1,37 @@
+package health
+
+import (
+	"DataPoller/internal/common/infrastructure/logging"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Register adds /healthz and /readyz to the mux. Both answer with the Report of the
+// monitor, with status 503 when the process is not live or not ready respectively.
+func Register(mux *http.ServeMux, monitor *Monitor, staleness time.Duration) {
+	mux.HandleFunc("/healthz", func(writer http.ResponseWriter, request *http.Request) {
+		report := monitor.Report(staleness)
+		writeReport(writer, report, report.Live())
+	})
+	mux.HandleFunc("/readyz", func(writer http.ResponseWriter, request *http.Request) {
+		report := monitor.Report(staleness)
+		writeReport(writer, report, report.Ready())
+	})
+}
+
+func writeReport(writer http.ResponseWriter, report Report, ok bool) {
+	status := http.StatusOK
+	report.Status = "ok"
+	if !ok {
+		status = http.StatusServiceUnavailable
+		report.Status = "unavailable"
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(report); err != nil {
+		slog.Warn("Error writing health report", logging.Error(err))
+	}
+}
@@ -0,0 +1,299 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultStaleness is how long a subscribed pair may go without a quote before the
+// process is reported as not ready.
+const DefaultStaleness = time.Minute
+
+type feedKey struct {
+	dataSource string
+	symbolPair string
+}
+
+type connectionState struct {
+	connected bool
+	gaveUp    bool
+	since     time.Time
+	err       string
+}
+
+type writerState struct {
+	lastFlush time.Time
+	err       string
+}
+
+// Monitor collects what the health endpoints report: the last quote of every
+// subscribed pair, the state of every connection and the last flush of every writer.
+type Monitor struct {
+	mu          sync.Mutex
+	started     time.Time
+	quotes      map[feedKey]time.Time
+	connections map[string]connectionState
+	writers     map[string]writerState
+	// paused holds the pairs whose connection is in an exchange maintenance, resumed
+	// when they came out of it.
+	paused  map[feedKey]bool
+	resumed map[feedKey]time.Time
+}
+
+func NewMonitor() *Monitor {
+	return &Monitor{
+		started:     time.Now(),
+		quotes:      make(map[feedKey]time.Time),
+		connections: make(map[string]connectionState),
+		writers:     make(map[string]writerState),
+		paused:      make(map[feedKey]bool),
+		resumed:     make(map[feedKey]time.Time),
+	}
+}
+
+// DefaultMonitor is the monitor the pollers, connections and writers of the process
+// report to.
+var DefaultMonitor = NewMonitor()
+
+// ExpectQuotes makes the pair of the data source count towards readiness. It is stale
+// until its first quote arrives.
+func (monitor *Monitor) ExpectQuotes(dataSource string, symbolPair string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	key := feedKey{dataSource: dataSource, symbolPair: symbolPair}
+	if _, found := monitor.quotes[key]; !found {
+		monitor.quotes[key] = time.Time{}
+	}
+}
+
+// ForgetQuotes stops the pair of the data source from counting towards readiness, e.g.
+// after it was unsubscribed.
+func (monitor *Monitor) ForgetQuotes(dataSource string, symbolPair string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	key := feedKey{dataSource: dataSource, symbolPair: symbolPair}
+	delete(monitor.quotes, key)
+	delete(monitor.paused, key)
+	delete(monitor.resumed, key)
+}
+
+// PauseQuotes stops the pair of the data source from going stale while the exchange
+// connection streaming it is in maintenance and sends no quotes.
+func (monitor *Monitor) PauseQuotes(dataSource string, symbolPair string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	monitor.paused[feedKey{dataSource: dataSource, symbolPair: symbolPair}] = true
+}
+
+// ResumeQuotes ends a PauseQuotes. The pair gets the staleness period from now on to
+// deliver its next quote.
+func (monitor *Monitor) ResumeQuotes(dataSource string, symbolPair string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	key := feedKey{dataSource: dataSource, symbolPair: symbolPair}
+	if monitor.paused[key] {
+		delete(monitor.paused, key)
+		monitor.resumed[key] = time.Now()
+	}
+}
+
+func (monitor *Monitor) QuoteReceived(dataSource string, symbolPair string, receivedAt time.Time) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	monitor.quotes[feedKey{dataSource: dataSource, symbolPair: symbolPair}] = receivedAt
+}
+
+func (monitor *Monitor) ConnectionUp(connection string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	monitor.connections[connection] = connectionState{connected: true, since: time.Now()}
+}
+
+// ConnectionDown marks the connection as reconnecting after err.
+func (monitor *Monitor) ConnectionDown(connection string, err error) {
+	monitor.setConnectionDown(connection, err, false)
+}
+
+// ConnectionGaveUp marks the connection as dead for good, its poller will not retry.
+func (monitor *Monitor) ConnectionGaveUp(connection string, err error) {
+	monitor.setConnectionDown(connection, err, true)
+}
+
+func (monitor *Monitor) setConnectionDown(connection string, err error, gaveUp bool) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	state := monitor.connections[connection]
+	if state.connected || state.since.IsZero() || gaveUp {
+		state.since = time.Now()
+	}
+	state.connected = false
+	state.gaveUp = state.gaveUp || gaveUp
+	if err != nil {
+		state.err = err.Error()
+	}
+	monitor.connections[connection] = state
+}
+
+// ConnectionClosed forgets a connection that was shut down on purpose.
+func (monitor *Monitor) ConnectionClosed(connection string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	delete(monitor.connections, connection)
+}
+
+// WriterStarted lists a writer that has not flushed yet as healthy.
+func (monitor *Monitor) WriterStarted(writer string) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+	monitor.writers[writer] = writerState{}
+}
+
+// WriterFlushed records the outcome of a flush of the writer, err is nil on success.
+func (monitor *Monitor) WriterFlushed(writer string, err error) {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	state := writerState{lastFlush: time.Now()}
+	if err != nil {
+		state = monitor.writers[writer]
+		state.err = err.Error()
+	}
+	monitor.writers[writer] = state
+}
+
+// Report is the body of the health endpoints.
+type Report struct {
+	Status          string           `json:"status"`
+	StalePairs      []StalePair      `json:"stale_pairs"`
+	DeadConnections []DeadConnection `json:"dead_connections"`
+	Writers         []WriterStatus   `json:"writers"`
+}
+
+type StalePair struct {
+	DataSource string     `json:"data_source"`
+	SymbolPair string     `json:"symbol_pair"`
+	LastQuote  *time.Time `json:"last_quote"`
+	AgeSeconds float64    `json:"age_seconds"`
+}
+
+type DeadConnection struct {
+	Connection string    `json:"connection"`
+	Since      time.Time `json:"since"`
+	GaveUp     bool      `json:"gave_up"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type WriterStatus struct {
+	Writer    string     `json:"writer"`
+	Healthy   bool       `json:"healthy"`
+	LastFlush *time.Time `json:"last_flush"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Report lists the pairs without a quote for longer than staleness, leaving out paused
+// ones, the connections that are not connected and the status
+// of every writer.
+func (monitor *Monitor) Report(staleness time.Duration) Report {
+	monitor.mu.Lock()
+	defer monitor.mu.Unlock()
+
+	now := time.Now()
+	report := Report{
+		StalePairs:      []StalePair{},
+		DeadConnections: []DeadConnection{},
+		Writers:         []WriterStatus{},
+	}
+
+	for key, lastQuote := range monitor.quotes {
+		if monitor.paused[key] {
+			continue
+		}
+		if !lastQuote.IsZero() && now.Sub(lastQuote) <= staleness {
+			continue
+		}
+		if resumed := monitor.resumed[key]; now.Sub(resumed) <= staleness {
+			continue
+		}
+
+		// Pairs that never had a quote are as old as the process.
+		since := lastQuote
+		if since.IsZero() {
+			since = monitor.started
+		}
+
+		stale := StalePair{
+			DataSource: key.dataSource,
+			SymbolPair: key.symbolPair,
+			AgeSeconds: now.Sub(since).Seconds(),
+		}
+		if !lastQuote.IsZero() {
+			stale.LastQuote = &lastQuote
+		}
+		report.StalePairs = append(report.StalePairs, stale)
+	}
+	sort.Slice(report.StalePairs, func(i, j int) bool {
+		if report.StalePairs[i].DataSource != report.StalePairs[j].DataSource {
+			return report.StalePairs[i].DataSource < report.StalePairs[j].DataSource
+		}
+		return report.StalePairs[i].SymbolPair < report.StalePairs[j].SymbolPair
+	})
+
+	for connection, state := range monitor.connections {
+		if state.connected {
+			continue
+		}
+		report.DeadConnections = append(report.DeadConnections, DeadConnection{
+			Connection: connection,
+			Since:      state.since,
+			GaveUp:     state.gaveUp,
+			Error:      state.err,
+		})
+	}
+	sort.Slice(report.DeadConnections, func(i, j int) bool {
+		return report.DeadConnections[i].Connection < report.DeadConnections[j].Connection
+	})
+
+	for writer, state := range monitor.writers {
+		status := WriterStatus{Writer: writer, Healthy: state.err == "", Error: state.err}
+		if !state.lastFlush.IsZero() {
+			lastFlush := state.lastFlush
+			status.LastFlush = &lastFlush
+		}
+		report.Writers = append(report.Writers, status)
+	}
+	sort.Slice(report.Writers, func(i, j int) bool {
+		return report.Writers[i].Writer < report.Writers[j].Writer
+	})
+
+	return report
+}
+
+// Live reports whether no connection gave up. Other failures are retried and only
+// affect readiness.
+func (report Report) Live() bool {
+	for _, connection := range report.DeadConnections {
+		if connection.GaveUp {
+			return false
+		}
+	}
+	return true
+}
+
+// Ready reports whether every pair is fresh, every connection is up and every writer
+// flushed successfully.
+func (report Report) Ready() bool {
+	if len(report.StalePairs) > 0 || len(report.DeadConnections) > 0 {
+		return false
+	}
+	for _, writer := range report.Writers {
+		if !writer.Healthy {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"time"
+)
+
+// quotesWriter reports the quotes a data source writes to the monitor.
+type quotesWriter struct {
+	repositories.CryptoQuotesWriter
+	monitor    *Monitor
+	dataSource string
+}
+
+// TrackQuotes expects quotes for the pairs of the data source and returns a writer
+// that reports the quotes written through it to the monitor.
+func TrackQuotes(monitor *Monitor, dataSource entities.DataSource,
+	writer repositories.CryptoQuotesWriter) repositories.CryptoQuotesWriter {
+	for _, pair := range dataSource.SymbolPairs {
+		monitor.ExpectQuotes(dataSource.Name, symbolPairName(pair))
+	}
+	return &quotesWriter{CryptoQuotesWriter: writer, monitor: monitor, dataSource: dataSource.Name}
+}
+
+// UpdateExpectedQuotes follows a change of the pairs a data source streams tickers for.
+func UpdateExpectedQuotes(monitor *Monitor, dataSource string, added []entities.SymbolPair,
+	removed []entities.SymbolPair) {
+	for _, pair := range removed {
+		monitor.ForgetQuotes(dataSource, symbolPairName(pair))
+	}
+	for _, pair := range added {
+		monitor.ExpectQuotes(dataSource, symbolPairName(pair))
+	}
+}
+
+// PauseExpectedQuotes pauses the staleness of the pairs while the exchange connection
+// streaming them is in maintenance.
+func PauseExpectedQuotes(monitor *Monitor, dataSource string, pairs []entities.SymbolPair) {
+	for _, pair := range pairs {
+		monitor.PauseQuotes(dataSource, symbolPairName(pair))
+	}
+}
+
+// ResumeExpectedQuotes ends a PauseExpectedQuotes of the pairs.
+func ResumeExpectedQuotes(monitor *Monitor, dataSource string, pairs []entities.SymbolPair) {
+	for _, pair := range pairs {
+		monitor.ResumeQuotes(dataSource, symbolPairName(pair))
+	}
+}
+
+func (writer *quotesWriter) Write(quotes []entities.CryptoQuote) error {
+	if err := writer.CryptoQuotesWriter.Write(quotes); err != nil {
+		return err
+	}
+
+	for _, quote := range quotes {
+		receivedAt := quote.ReceivedAt
+		if receivedAt.IsZero() {
+			receivedAt = time.Now()
+		}
+		writer.monitor.QuoteReceived(writer.dataSource, symbolPairName(quote.SymbolPair), receivedAt)
+	}
+	return nil
+}
+
+func symbolPairName(pair entities.SymbolPair) string {
+	return pair.BaseSymbol.Name + pair.QuoteSymbol.Name
+}
@@ -0,0 +1,131 @@
+package logging
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+)
+
+// Attribute keys shared by all log records.
+const (
+	DataSourceKey   = "data_source"
+	SymbolPairKey   = "symbol_pair"
+	ChanIdKey       = "chan_id"
+	ConnectionIdKey = "connection_id"
+	ErrorKey        = "error"
+)
+
+// Options configure the default logger, see Setup.
+type Options struct {
+	// Level is "debug", "info", "warn" or "error".
+	Level string `yaml:"level"`
+	// Format is "text" or "json".
+	Format   string          `yaml:"format"`
+	Sampling SamplingOptions `yaml:"sampling"`
+}
+
+// SamplingOptions thin out debug records, which some feeds produce for every message.
+// Within every Interval the first First records of a message are logged, then every
+// Thereafter-th. A First of zero disables sampling.
+type SamplingOptions struct {
+	First      int           `yaml:"first"`
+	Thereafter int           `yaml:"thereafter"`
+	Interval   time.Duration `yaml:"interval"`
+}
+
+var DefaultOptions = Options{
+	Level:  "info",
+	Format: "text",
+	Sampling: SamplingOptions{
+		First:      10,
+		Thereafter: 100,
+		Interval:   time.Second,
+	},
+}
+
+// Setup makes a logger with the options the slog default, which the log package
+// writes through as well. Empty options fall back to DefaultOptions.
+func Setup(options Options) error {
+	handler, err := NewHandler(os.Stderr, options)
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(handler))
+	return nil
+}
+
+func NewHandler(writer io.Writer, options Options) (slog.Handler, error) {
+	if options.Level == "" {
+		options.Level = DefaultOptions.Level
+	}
+	if options.Format == "" {
+		options.Format = DefaultOptions.Format
+	}
+	if options.Sampling == (SamplingOptions{}) {
+		options.Sampling = DefaultOptions.Sampling
+	}
+
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+
+	var level slog.Level
+	level.UnmarshalText([]byte(options.Level))
+	handlerOptions := &slog.HandlerOptions{Level: level}
+
+	var handler slog.Handler
+	if strings.EqualFold(options.Format, "json") {
+		handler = slog.NewJSONHandler(writer, handlerOptions)
+	} else {
+		handler = slog.NewTextHandler(writer, handlerOptions)
+	}
+
+	if options.Sampling.First > 0 {
+		handler = newSamplingHandler(handler, options.Sampling)
+	}
+	return handler, nil
+}
+
+// Validate checks the level and format. Empty values are valid, they fall back to
+// DefaultOptions.
+func (options Options) Validate() error {
+	if options.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(options.Level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", options.Level, err)
+		}
+	}
+
+	switch strings.ToLower(options.Format) {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("invalid log format %q, expected text or json", options.Format)
+	}
+
+	if options.Sampling.First < 0 || options.Sampling.Thereafter < 0 || options.Sampling.Interval < 0 {
+		return fmt.Errorf("invalid sampling %+v, values must not be negative", options.Sampling)
+	}
+	return nil
+}
+
+// SymbolPair is the symbol_pair attribute of the pair, e.g. BTCUSDT.
+func SymbolPair(pair entities.SymbolPair) slog.Attr {
+	return slog.String(SymbolPairKey, pair.BaseSymbol.Name+pair.QuoteSymbol.Name)
+}
+
+func Error(err error) slog.Attr {
+	return slog.Any(ErrorKey, err)
+}
+
+// SymbolPairs is the symbol_pairs attribute listing the pairs, e.g. [BTCUSDT ETHUSDT].
+func SymbolPairs(pairs []entities.SymbolPair) slog.Attr {
+	names := make([]string, len(pairs))
+	for i, pair := range pairs {
+		names[i] = pair.BaseSymbol.Name + pair.QuoteSymbol.Name
+	}
+	return slog.Any(SymbolPairKey+"s", names)
+}
@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// samplingHandler drops debug records once a message was logged SamplingOptions.First
+// times within the interval. Records above debug are always passed on.
+type samplingHandler struct {
+	slog.Handler
+	options SamplingOptions
+	// counters is shared with the handlers derived through WithAttrs and WithGroup,
+	// so a message is sampled the same for every connection.
+	counters *sampleCounters
+}
+
+type sampleCounters struct {
+	mu     sync.Mutex
+	counts map[string]*sampleCount
+}
+
+type sampleCount struct {
+	windowStart time.Time
+	count       int
+}
+
+func newSamplingHandler(handler slog.Handler, options SamplingOptions) *samplingHandler {
+	return &samplingHandler{
+		Handler:  handler,
+		options:  options,
+		counters: &sampleCounters{counts: make(map[string]*sampleCount)},
+	}
+}
+
+func (handler *samplingHandler) Handle(ctx context.Context, record slog.Record) error {
+	if record.Level > slog.LevelDebug || handler.counters.allow(record, handler.options) {
+		return handler.Handler.Handle(ctx, record)
+	}
+	return nil
+}
+
+func (handler *samplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &samplingHandler{Handler: handler.Handler.WithAttrs(attrs), options: handler.options, counters: handler.counters}
+}
+
+func (handler *samplingHandler) WithGroup(name string) slog.Handler {
+	return &samplingHandler{Handler: handler.Handler.WithGroup(name), options: handler.options, counters: handler.counters}
+}
+
+func (counters *sampleCounters) allow(record slog.Record, options SamplingOptions) bool {
+	counters.mu.Lock()
+	defer counters.mu.Unlock()
+
+	counter, found := counters.counts[record.Message]
+	if !found || record.Time.Sub(counter.windowStart) >= options.Interval {
+		counter = &sampleCount{windowStart: record.Time}
+		counters.counts[record.Message] = counter
+	}
+
+	counter.count++
+	if counter.count <= options.First {
+		return true
+	}
+	return options.Thereafter > 0 && (counter.count-options.First)%options.Thereafter == 0
+}
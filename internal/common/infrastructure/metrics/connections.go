@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	reconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "websocket_reconnects_total",
+		Help:      "Times a websocket connection was re-established after failing.",
+	}, []string{"connection"})
+	activeConnections = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "datapoller",
+		Name:      "websocket_active_connections",
+		Help:      "Open websocket connections, two while a connection is rotated.",
+	}, []string{"connection"})
+)
+
+func Reconnected(connection string) {
+	reconnects.WithLabelValues(connection).Inc()
+}
+
+func ConnectionOpened(connection string) {
+	activeConnections.WithLabelValues(connection).Inc()
+}
+
+func ConnectionClosed(connection string) {
+	activeConnections.WithLabelValues(connection).Dec()
+}
@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var feedLabels = []string{"data_source", "symbol_pair"}
+
+var (
+	messagesReceived = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "messages_received_total",
+		Help:      "Messages read from the exchange, symbol_pair is empty for messages of no pair.",
+	}, feedLabels)
+	parseErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "parse_errors_total",
+		Help:      "Messages that could not be decoded or converted.",
+	}, feedLabels)
+	quotesWritten = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "quotes_written_total",
+		Help:      "Quotes handed to the quotes writer.",
+	}, feedLabels)
+	writeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "write_errors_total",
+		Help:      "Quotes, trades and order books the writers refused.",
+	}, feedLabels)
+	subscribedChannels = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "datapoller",
+		Name:      "subscribed_channels",
+		Help:      "Channels the exchange confirmed on a connection.",
+	}, []string{"data_source", "connection"})
+)
+
+// lastMessages backs datapoller_last_message_age_seconds, which is computed on scrape.
+var lastMessages = newLastMessageCollector()
+
+func init() {
+	prometheus.MustRegister(lastMessages)
+}
+
+// Feed records the metrics of the messages of one data source.
+type Feed struct {
+	dataSource string
+}
+
+func NewFeed(dataSource string) *Feed {
+	return &Feed{dataSource: dataSource}
+}
+
+// MessageReceived counts a message of the pair, the zero SymbolPair for messages such
+// as subscription responses and heartbeats.
+func (feed *Feed) MessageReceived(pair entities.SymbolPair, receivedAt time.Time) {
+	label := symbolPairLabel(pair)
+	messagesReceived.WithLabelValues(feed.dataSource, label).Inc()
+	lastMessages.set(feed.dataSource, label, receivedAt)
+}
+
+func (feed *Feed) ParseError(pair entities.SymbolPair) {
+	parseErrors.WithLabelValues(feed.dataSource, symbolPairLabel(pair)).Inc()
+}
+
+func (feed *Feed) QuoteWritten(pair entities.SymbolPair) {
+	quotesWritten.WithLabelValues(feed.dataSource, symbolPairLabel(pair)).Inc()
+}
+
+func (feed *Feed) WriteError(pair entities.SymbolPair) {
+	writeErrors.WithLabelValues(feed.dataSource, symbolPairLabel(pair)).Inc()
+}
+
+func (feed *Feed) SetSubscribedChannels(connection string, count int) {
+	subscribedChannels.WithLabelValues(feed.dataSource, connection).Set(float64(count))
+}
+
+func symbolPairLabel(pair entities.SymbolPair) string {
+	return pair.BaseSymbol.Name + pair.QuoteSymbol.Name
+}
+
+type feedKey struct {
+	dataSource string
+	symbolPair string
+}
+
+// lastMessageCollector exports the age of the last message of every data source and
+// pair, so alerts do not depend on the clock of the scraper.
+type lastMessageCollector struct {
+	desc  *prometheus.Desc
+	mu    sync.Mutex
+	times map[feedKey]time.Time
+}
+
+func newLastMessageCollector() *lastMessageCollector {
+	return &lastMessageCollector{
+		desc: prometheus.NewDesc("datapoller_last_message_age_seconds",
+			"Seconds since the last message was received.", feedLabels, nil),
+		times: make(map[feedKey]time.Time),
+	}
+}
+
+func (collector *lastMessageCollector) set(dataSource string, symbolPair string, receivedAt time.Time) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.times[feedKey{dataSource: dataSource, symbolPair: symbolPair}] = receivedAt
+}
+
+func (collector *lastMessageCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- collector.desc
+}
+
+func (collector *lastMessageCollector) Collect(metrics chan<- prometheus.Metric) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	now := time.Now()
+	for key, receivedAt := range collector.times {
+		metrics <- prometheus.MustNewConstMetric(collector.desc, prometheus.GaugeValue,
+			now.Sub(receivedAt).Seconds(), key.dataSource, key.symbolPair)
+	}
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// DefaultAddress is the address the metrics endpoint listens on unless configured.
+const DefaultAddress = ":9090"
+
+// shutdownTimeout bounds how long Serve waits for running scrapes on shutdown.
+const shutdownTimeout = 5 * time.Second
+
+// feedLatency is the time between the exchange producing an update and the poller
+// reading it from the connection, including clock skew between both hosts.
+var feedLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "datapoller",
+	Name:      "feed_latency_seconds",
+	Help:      "Delay between the exchange timestamp of an update and its local receive time.",
+	Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}, []string{"data_source"})
+
+// ObserveFeedLatency records the feed latency of an update of the data source. Updates
+// without an exchange timestamp are ignored.
+func ObserveFeedLatency(dataSource string, exchangeTime time.Time, receivedAt time.Time) {
+	if exchangeTime.IsZero() || receivedAt.IsZero() {
+		return
+	}
+	feedLatency.WithLabelValues(dataSource).Observe(receivedAt.Sub(exchangeTime).Seconds())
+}
+
+// NewServeMux returns a mux serving the registered metrics on /metrics.
+func NewServeMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	return mux
+}
+
+// Serve serves the handler on the address until ctx is cancelled.
+func Serve(ctx context.Context, address string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	stop := context.AfterFunc(ctx, func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("Error shutting down metrics server", logging.Error(err))
+		}
+	})
+	defer stop()
+
+	slog.Info("Serving metrics", "address", address)
+	if err := server.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	questFlushDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "datapoller",
+		Name:      "questdb_flush_duration_seconds",
+		Help:      "Time taken to send a batch to QuestDB, including a reconnect.",
+		Buckets:   prometheus.ExponentialBuckets(.001, 2, 14),
+	}, []string{"writer"})
+	questRowsFlushed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "questdb_rows_flushed_total",
+		Help:      "Rows sent to QuestDB.",
+	}, []string{"writer"})
+	questRowsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "questdb_rows_dropped_total",
+		Help:      "Rows dropped because QuestDB could not be reached.",
+	}, []string{"writer"})
+	questRowsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "questdb_rows_skipped_total",
+		Help:      "Rows skipped because they could not be stored, per pair.",
+	}, []string{"writer", "market", "symbol_pair"})
+)
+
+// ObserveQuestFlush records a flushed batch of the writer and whether it was dropped.
+func ObserveQuestFlush(writer string, rows int, duration time.Duration, dropped bool) {
+	questFlushDuration.WithLabelValues(writer).Observe(duration.Seconds())
+	if dropped {
+		questRowsDropped.WithLabelValues(writer).Add(float64(rows))
+	} else {
+		questRowsFlushed.WithLabelValues(writer).Add(float64(rows))
+	}
+}
+
+func QuestRowSkipped(writer string, market string, symbolPair string) {
+	questRowsSkipped.WithLabelValues(writer, market, symbolPair).Inc()
+}
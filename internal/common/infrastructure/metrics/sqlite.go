@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	sqliteFlushDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "datapoller",
+		Name:      "sqlite_flush_duration_seconds",
+		Help:      "Time taken to insert a batch into SQLite.",
+		Buckets:   prometheus.ExponentialBuckets(.001, 2, 14),
+	}, []string{"writer"})
+	sqliteRowsFlushed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "sqlite_rows_flushed_total",
+		Help:      "Rows inserted into SQLite.",
+	}, []string{"writer"})
+	sqliteRowsDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "sqlite_rows_dropped_total",
+		Help:      "Rows dropped because their batch could not be inserted.",
+	}, []string{"writer"})
+	sqliteRowsSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "datapoller",
+		Name:      "sqlite_rows_skipped_total",
+		Help:      "Rows skipped because they could not be stored, per pair.",
+	}, []string{"writer", "market", "symbol_pair"})
+)
+
+// ObserveSqliteFlush records a flushed batch of the writer and whether it was dropped.
+func ObserveSqliteFlush(writer string, rows int, duration time.Duration, dropped bool) {
+	sqliteFlushDuration.WithLabelValues(writer).Observe(duration.Seconds())
+	if dropped {
+		sqliteRowsDropped.WithLabelValues(writer).Add(float64(rows))
+	} else {
+		sqliteRowsFlushed.WithLabelValues(writer).Add(float64(rows))
+	}
+}
+
+func SqliteRowSkipped(writer string, market string, symbolPair string) {
+	sqliteRowsSkipped.WithLabelValues(writer, market, symbolPair).Inc()
+}
@@ -0,0 +1,413 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed postgres/*.sql quest/*.sql
+var files embed.FS
+
+// Targets of the migrations, named after the directory holding their files.
+const (
+	// PostgresTarget holds the tds catalog of the main database.
+	PostgresTarget = "postgres"
+	// QuestTarget holds the QuestDB tables the writers fill.
+	QuestTarget = "quest"
+)
+
+// Targets lists the targets in the order Up migrates them.
+var Targets = []string{PostgresTarget, QuestTarget}
+
+// stateTable records the applied migrations of every target. It lives in the main
+// database because QuestDB cannot delete the record of a reverted migration.
+const stateTable = "public.datapoller_migrations"
+
+// fileNamePattern matches <version>_<name>.up.sql and <version>_<name>.down.sql.
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Target  string
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Migration
+	// AppliedAt is zero while the migration is pending.
+	AppliedAt time.Time
+}
+
+func (status Status) Applied() bool {
+	return !status.AppliedAt.IsZero()
+}
+
+// Load reads the embedded migrations of the target ordered by version. Every version
+// needs both an up and a down file.
+func Load(target string) ([]Migration, error) {
+	entries, err := fs.ReadDir(files, target)
+	if err != nil {
+		return nil, fmt.Errorf("unknown migration target %q", target)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %s/%s", target, entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := files.ReadFile(path.Join(target, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Target: target, Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %s/%d has the names %s and %s", target, version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %s/%d needs an up and a down file", target, migration.Version)
+		}
+		migrations = append(migrations, *migration)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return a.Version - b.Version })
+	return migrations, nil
+}
+
+// Migrator applies and reverts the migrations of the targets it was given a database
+// for. Migrations of the main database run in one transaction with their record, the
+// ones of other databases are recorded once all their statements succeeded and
+// therefore only use statements that can be repeated.
+type Migrator struct {
+	state      *sql.DB
+	databases  map[string]*sql.DB
+	migrations map[string][]Migration
+}
+
+// NewMigrator migrates the targets of databases, keyed by PostgresTarget and
+// QuestTarget, and records them in mainDatabase.
+func NewMigrator(mainDatabase *sql.DB, databases map[string]*sql.DB) (*Migrator, error) {
+	migrator := &Migrator{
+		state:      mainDatabase,
+		databases:  databases,
+		migrations: make(map[string][]Migration),
+	}
+	for target := range databases {
+		migrations, err := Load(target)
+		if err != nil {
+			return nil, err
+		}
+		migrator.migrations[target] = migrations
+	}
+	return migrator, nil
+}
+
+// targets returns the migrated targets in the order of Targets.
+func (migrator *Migrator) targets() []string {
+	var targets []string
+	for _, target := range Targets {
+		if _, found := migrator.databases[target]; found {
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// Status lists every migration of the targets with the time it was applied.
+func (migrator *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []Status
+	for _, target := range migrator.targets() {
+		for _, migration := range migrator.migrations[target] {
+			statuses = append(statuses, Status{
+				Migration: migration,
+				AppliedAt: applied[target][migration.Version],
+			})
+		}
+	}
+	return statuses, nil
+}
+
+// Up applies the pending migrations of every target in version order.
+func (migrator *Migrator) Up(ctx context.Context) error {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range migrator.targets() {
+		for _, migration := range migrator.migrations[target] {
+			if _, found := applied[target][migration.Version]; found {
+				continue
+			}
+			if err := migrator.run(ctx, migration, true); err != nil {
+				return fmt.Errorf("failed to apply migration %s/%d_%s: %w", target, migration.Version, migration.Name, err)
+			}
+			slog.Info("Applied migration", "target", target, "version", migration.Version, "name", migration.Name)
+		}
+	}
+	return nil
+}
+
+// Down reverts the last steps applied migrations, latest first.
+func (migrator *Migrator) Down(ctx context.Context, steps int) error {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	var applied []Status
+	for _, status := range statuses {
+		if status.Applied() {
+			applied = append(applied, status)
+		}
+	}
+	slices.SortStableFunc(applied, func(a, b Status) int {
+		if compared := b.AppliedAt.Compare(a.AppliedAt); compared != 0 {
+			return compared
+		}
+		return b.Version - a.Version
+	})
+
+	for _, status := range applied[:min(steps, len(applied))] {
+		migration := status.Migration
+		if err := migrator.run(ctx, migration, false); err != nil {
+			return fmt.Errorf("failed to revert migration %s/%d_%s: %w", migration.Target, migration.Version,
+				migration.Name, err)
+		}
+		slog.Info("Reverted migration", "target", migration.Target, "version", migration.Version,
+			"name", migration.Name)
+	}
+	return nil
+}
+
+func (migrator *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	script, record := migration.Down, "DELETE FROM "+stateTable+" WHERE target = $1 AND version = $2"
+	if up {
+		script, record = migration.Up, "INSERT INTO "+stateTable+" (target, version, name) VALUES ($1, $2, $3)"
+	}
+	args := []any{migration.Target, migration.Version}
+	if up {
+		args = append(args, migration.Name)
+	}
+
+	db := migrator.databases[migration.Target]
+	if db != migrator.state {
+		for _, statement := range statements(script) {
+			if _, err := db.ExecContext(ctx, statement); err != nil {
+				return err
+			}
+			if up && migration.Target == QuestTarget {
+				if err := checkColumns(ctx, db, statement); err != nil {
+					return err
+				}
+			}
+		}
+		_, err := migrator.state.ExecContext(ctx, record, args...)
+		return err
+	}
+
+	tx, err := migrator.state.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// checkColumns fails when the statement creates a table that already existed with other
+// columns, which CREATE TABLE IF NOT EXISTS leaves as they are. QuestDB creates tables
+// on the first line protocol write, so tables written before the migrations existed
+// have DOUBLE columns where older writers sent floats, and lack the newer columns.
+// Such a table has to be converted by hand:
+//
+//	RENAME TABLE crypto_quotes TO crypto_quotes_old;
+//	-- datapoller migrate up --target quest
+//	INSERT INTO crypto_quotes SELECT ... FROM crypto_quotes_old;
+//	DROP TABLE crypto_quotes_old;
+//
+// where the SELECT scales the old values to the columns of the new table. Columns the
+// migration does not declare are left alone.
+func checkColumns(ctx context.Context, db *sql.DB, statement string) error {
+	table, expected, ok := tableColumns(statement)
+	if !ok {
+		return nil
+	}
+
+	// The table name is a word, see createTablePattern.
+	rows, err := db.QueryContext(ctx, `SELECT "column", "type" FROM table_columns('`+table+`')`)
+	if err != nil {
+		return fmt.Errorf("failed to read the columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]string)
+	for rows.Next() {
+		var column, columnType string
+		if err := rows.Scan(&column, &columnType); err != nil {
+			return err
+		}
+		existing[strings.ToLower(column)] = columnType
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var mismatches []string
+	for _, column := range expected {
+		columnType, found := existing[strings.ToLower(column.name)]
+		if !found {
+			mismatches = append(mismatches, column.name+" is missing")
+		} else if !strings.EqualFold(columnType, column.columnType) {
+			mismatches = append(mismatches, fmt.Sprintf("%s is %s instead of %s", column.name, columnType,
+				column.columnType))
+		}
+	}
+	if len(mismatches) > 0 {
+		return fmt.Errorf("table %s already exists with other columns (%s), rename it, migrate again and "+
+			"copy its rows into the new table", table, strings.Join(mismatches, ", "))
+	}
+	return nil
+}
+
+// createTablePattern matches the start of a CREATE TABLE IF NOT EXISTS statement up to
+// the opening parenthesis of its columns.
+var createTablePattern = regexp.MustCompile(`(?i)^CREATE\s+TABLE\s+IF\s+NOT\s+EXISTS\s+(\w+)\s*\(`)
+
+type column struct {
+	name       string
+	columnType string
+}
+
+// tableColumns returns the table a CREATE TABLE IF NOT EXISTS statement creates and the
+// columns it declares. It reports false for any other statement.
+func tableColumns(statement string) (string, []column, bool) {
+	match := createTablePattern.FindStringSubmatchIndex(statement)
+	if match == nil {
+		return "", nil, false
+	}
+	table := statement[match[2]:match[3]]
+
+	var columns []column
+	depth, start := 0, match[1]
+	for i := match[1]; i < len(statement); i++ {
+		switch statement[i] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return table, appendColumn(columns, statement[start:i]), true
+			}
+			depth--
+		case ',':
+			if depth == 0 {
+				columns = appendColumn(columns, statement[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return table, columns, true
+}
+
+// appendColumn appends the column declared as "<name> <type>" to columns.
+func appendColumn(columns []column, declaration string) []column {
+	fields := strings.Fields(declaration)
+	if len(fields) < 2 {
+		return columns
+	}
+	return append(columns, column{name: fields[0], columnType: strings.Join(fields[1:], " ")})
+}
+
+// applied returns when each applied migration was applied, by target and version.
+func (migrator *Migrator) applied(ctx context.Context) (map[string]map[int]time.Time, error) {
+	_, err := migrator.state.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+stateTable+" ("+
+		"target text NOT NULL, "+
+		"version integer NOT NULL, "+
+		"name text NOT NULL, "+
+		"applied_at timestamptz NOT NULL DEFAULT clock_timestamp(), "+
+		"PRIMARY KEY (target, version))")
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := migrator.state.QueryContext(ctx, "SELECT target, version, applied_at FROM "+stateTable)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[string]map[int]time.Time)
+	for rows.Next() {
+		var target string
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&target, &version, &appliedAt); err != nil {
+			return nil, err
+		}
+		if applied[target] == nil {
+			applied[target] = make(map[int]time.Time)
+		}
+		applied[target][version] = appliedAt
+	}
+	return applied, rows.Err()
+}
+
+// statements splits a script at the semicolons ending a line, for databases that
+// take a single statement at a time. Comment lines are dropped.
+func statements(script string) []string {
+	var statements []string
+	var statement strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		statement.WriteString(line)
+		statement.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(statement.String()), ";"))
+			statement.Reset()
+		}
+	}
+	if rest := strings.TrimSpace(statement.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+	return statements
+}
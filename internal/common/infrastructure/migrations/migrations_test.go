@@ -0,0 +1,107 @@
+package migrations
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{name: "empty", script: ""},
+		{name: "only comments", script: "-- nothing to do\n\n  -- really\n"},
+		{name: "single statement", script: "DROP TABLE IF EXISTS crypto_quotes;\n",
+			want: []string{"DROP TABLE IF EXISTS crypto_quotes"}},
+		{name: "without a final semicolon", script: "DROP TABLE crypto_quotes",
+			want: []string{"DROP TABLE crypto_quotes"}},
+		{
+			name:   "multi line statements",
+			script: "CREATE TABLE t\n(\n    a LONG,\n    b LONG\n) TIMESTAMP(b);\nDROP TABLE u;\n",
+			want:   []string{"CREATE TABLE t\n(\n    a LONG,\n    b LONG\n) TIMESTAMP(b)", "DROP TABLE u"},
+		},
+		{
+			name:   "comment lines inside a statement",
+			script: "-- header\nCREATE TABLE t\n(\n    -- the price\n    a LONG\n);\n",
+			want:   []string{"CREATE TABLE t\n(\n    a LONG\n)"},
+		},
+		{
+			name:   "semicolons inside a line do not split",
+			script: "SELECT 'a;b' FROM t;\n",
+			want:   []string{"SELECT 'a;b' FROM t"},
+		},
+		{
+			name:   "windows line endings",
+			script: "DROP TABLE a;\r\nDROP TABLE b;\r\n",
+			want:   []string{"DROP TABLE a", "DROP TABLE b"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := statements(test.script); !slices.Equal(got, test.want) {
+				t.Errorf("statements() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestTableColumns(t *testing.T) {
+	tests := []struct {
+		name      string
+		statement string
+		wantTable string
+		want      []column
+		wantOk    bool
+	}{
+		{name: "other statement", statement: "DROP TABLE IF EXISTS crypto_quotes"},
+		{name: "create without if not exists", statement: "CREATE TABLE t (a LONG)"},
+		{
+			name:      "quest table",
+			statement: "CREATE TABLE IF NOT EXISTS crypto_trades\n(\n    Base SYMBOL,\n    ts   TIMESTAMP\n) TIMESTAMP(ts) PARTITION BY DAY",
+			wantTable: "crypto_trades",
+			want:      []column{{name: "Base", columnType: "SYMBOL"}, {name: "ts", columnType: "TIMESTAMP"}},
+			wantOk:    true,
+		},
+		{
+			name:      "types with parameters",
+			statement: "create table if not exists t(a DECIMAL(18, 8), b GEOHASH(8c))",
+			wantTable: "t",
+			want:      []column{{name: "a", columnType: "DECIMAL(18, 8)"}, {name: "b", columnType: "GEOHASH(8c)"}},
+			wantOk:    true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			table, columns, ok := tableColumns(test.statement)
+			if ok != test.wantOk || table != test.wantTable || !slices.Equal(columns, test.want) {
+				t.Errorf("tableColumns() = %q, %v, %v, want %q, %v, %v", table, columns, ok,
+					test.wantTable, test.want, test.wantOk)
+			}
+		})
+	}
+}
+
+func TestLoadQuestMigrationsDeclareColumns(t *testing.T) {
+	migrations, err := Load(QuestTarget)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, migration := range migrations {
+		created := 0
+		for _, statement := range statements(migration.Up) {
+			if _, columns, ok := tableColumns(statement); ok {
+				created++
+				if len(columns) == 0 {
+					t.Errorf("%d_%s creates a table without columns", migration.Version, migration.Name)
+				}
+			}
+		}
+		if created == 0 {
+			t.Errorf("%d_%s creates no table whose columns migrate could check", migration.Version, migration.Name)
+		}
+	}
+}
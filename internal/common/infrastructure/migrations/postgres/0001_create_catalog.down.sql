@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS tds.data_source_symbol_pairs;
+DROP TABLE IF EXISTS tds.data_sources;
+DROP TABLE IF EXISTS tds.symbol_pairs;
+DROP TABLE IF EXISTS tds.symbols;
+DROP TABLE IF EXISTS tds.markets;
+DROP SCHEMA IF EXISTS tds;
@@ -0,0 +1,43 @@
+-- The catalog of the data sources and the symbol pairs they are polled for. The
+-- statements skip existing objects, so catalogs created before the migrations can be
+-- adopted by running them.
+
+CREATE SCHEMA IF NOT EXISTS tds;
+
+CREATE TABLE IF NOT EXISTS tds.markets
+(
+    id   serial PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS tds.symbols
+(
+    id   serial PRIMARY KEY,
+    name text NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS tds.symbol_pairs
+(
+    id               serial PRIMARY KEY,
+    market_id        integer NOT NULL REFERENCES tds.markets (id),
+    base_symbol_id   integer NOT NULL REFERENCES tds.symbols (id),
+    quoted_symbol_id integer NOT NULL REFERENCES tds.symbols (id),
+    UNIQUE (market_id, base_symbol_id, quoted_symbol_id)
+);
+
+CREATE TABLE IF NOT EXISTS tds.data_sources
+(
+    id                serial PRIMARY KEY,
+    name              text    NOT NULL UNIQUE,
+    connection_string text    NOT NULL,
+    login             text    NOT NULL DEFAULT '',
+    password          text    NOT NULL DEFAULT '',
+    rate_limit        integer NOT NULL DEFAULT 0 CHECK (rate_limit >= 0)
+);
+
+CREATE TABLE IF NOT EXISTS tds.data_source_symbol_pairs
+(
+    data_source_id integer NOT NULL REFERENCES tds.data_sources (id) ON DELETE CASCADE,
+    symbol_pair_id integer NOT NULL REFERENCES tds.symbol_pairs (id) ON DELETE CASCADE,
+    PRIMARY KEY (data_source_id, symbol_pair_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE tds.data_sources
+    DROP COLUMN IF EXISTS channels;
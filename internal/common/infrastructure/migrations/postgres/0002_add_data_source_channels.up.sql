@@ -0,0 +1,4 @@
+-- What the poller of a data source streams, see entities.DataSource.Channels. NULL
+-- streams tickers only.
+ALTER TABLE tds.data_sources
+    ADD COLUMN IF NOT EXISTS channels text[];
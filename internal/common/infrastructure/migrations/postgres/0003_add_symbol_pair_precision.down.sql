@@ -0,0 +1,4 @@
+ALTER TABLE tds.symbol_pairs
+    DROP COLUMN IF EXISTS price_precision,
+    DROP COLUMN IF EXISTS quantity_precision,
+    DROP COLUMN IF EXISTS lot_size;
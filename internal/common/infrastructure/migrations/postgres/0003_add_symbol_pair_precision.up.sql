@@ -0,0 +1,6 @@
+-- The decimal places quotes of a pair are stored with and the exchange lot size. NULL
+-- precisions use entities.DefaultPricePrecision and DefaultQuantityPrecision.
+ALTER TABLE tds.symbol_pairs
+    ADD COLUMN IF NOT EXISTS price_precision    integer CHECK (price_precision >= 0),
+    ADD COLUMN IF NOT EXISTS quantity_precision integer CHECK (quantity_precision >= 0),
+    ADD COLUMN IF NOT EXISTS lot_size           numeric CHECK (lot_size > 0);
@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS symbols_changed ON tds.symbols;
+DROP TRIGGER IF EXISTS markets_changed ON tds.markets;
+DROP TRIGGER IF EXISTS symbol_pairs_changed ON tds.symbol_pairs;
+DROP TRIGGER IF EXISTS data_source_symbol_pairs_changed ON tds.data_source_symbol_pairs;
+DROP TRIGGER IF EXISTS data_sources_changed ON tds.data_sources;
+DROP FUNCTION IF EXISTS tds.notify_data_source_changed();
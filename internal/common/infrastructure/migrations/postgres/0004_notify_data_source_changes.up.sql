@@ -0,0 +1,51 @@
+-- Notifies tds_data_sources_changed whenever the pairs of a data source may have
+-- changed, see postgresrepositories.PostgresDataSourcesWatcher. The payload is the id
+-- of the changed data source, or empty when a shared table changed and every data
+-- source has to reload.
+
+CREATE OR REPLACE FUNCTION tds.notify_data_source_changed() RETURNS trigger AS $$
+BEGIN
+    IF TG_TABLE_NAME = 'data_source_symbol_pairs' THEN
+        IF TG_OP <> 'INSERT' THEN
+            PERFORM pg_notify('tds_data_sources_changed', OLD.data_source_id::text);
+        END IF;
+        IF TG_OP <> 'DELETE' AND (TG_OP = 'INSERT' OR NEW.data_source_id <> OLD.data_source_id) THEN
+            PERFORM pg_notify('tds_data_sources_changed', NEW.data_source_id::text);
+        END IF;
+    ELSIF TG_TABLE_NAME = 'data_sources' THEN
+        IF TG_OP = 'DELETE' THEN
+            PERFORM pg_notify('tds_data_sources_changed', OLD.id::text);
+        ELSE
+            PERFORM pg_notify('tds_data_sources_changed', NEW.id::text);
+        END IF;
+    ELSE
+        PERFORM pg_notify('tds_data_sources_changed', '');
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS data_sources_changed ON tds.data_sources;
+CREATE TRIGGER data_sources_changed
+    AFTER INSERT OR UPDATE OR DELETE ON tds.data_sources
+    FOR EACH ROW EXECUTE FUNCTION tds.notify_data_source_changed();
+
+DROP TRIGGER IF EXISTS data_source_symbol_pairs_changed ON tds.data_source_symbol_pairs;
+CREATE TRIGGER data_source_symbol_pairs_changed
+    AFTER INSERT OR UPDATE OR DELETE ON tds.data_source_symbol_pairs
+    FOR EACH ROW EXECUTE FUNCTION tds.notify_data_source_changed();
+
+DROP TRIGGER IF EXISTS symbol_pairs_changed ON tds.symbol_pairs;
+CREATE TRIGGER symbol_pairs_changed
+    AFTER INSERT OR UPDATE OR DELETE ON tds.symbol_pairs
+    FOR EACH STATEMENT EXECUTE FUNCTION tds.notify_data_source_changed();
+
+DROP TRIGGER IF EXISTS markets_changed ON tds.markets;
+CREATE TRIGGER markets_changed
+    AFTER UPDATE OR DELETE ON tds.markets
+    FOR EACH STATEMENT EXECUTE FUNCTION tds.notify_data_source_changed();
+
+DROP TRIGGER IF EXISTS symbols_changed ON tds.symbols;
+CREATE TRIGGER symbols_changed
+    AFTER UPDATE OR DELETE ON tds.symbols
+    FOR EACH STATEMENT EXECUTE FUNCTION tds.notify_data_source_changed();
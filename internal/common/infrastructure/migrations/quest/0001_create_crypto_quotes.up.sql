@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS crypto_quotes
+(
+    Base          SYMBOL,
+    Quote         SYMBOL,
+    MarketName    SYMBOL,
+    BaseQuote     SYMBOL,
+    BaseId        LONG,
+    QuoteId       LONG,
+    MarketId      LONG,
+    TimeStamp     TIMESTAMP,
+    Rate          LONG,
+    OpenRate      LONG,
+    HighRate      LONG,
+    LowRate       LONG,
+    CloseRate     LONG,
+    Volume        LONG,
+    PriceScale    LONG,
+    QuantityScale LONG,
+    BidRate       LONG,
+    AskRate       LONG,
+    BidSize       LONG,
+    AskSize       LONG,
+    QuoteVolume   LONG,
+    ReceivedAt    TIMESTAMP,
+    ts            TIMESTAMP
+) TIMESTAMP(ts) PARTITION BY DAY;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS crypto_trades
+(
+    Base          SYMBOL,
+    Quote         SYMBOL,
+    MarketName    SYMBOL,
+    BaseQuote     SYMBOL,
+    Side          SYMBOL,
+    BaseId        LONG,
+    QuoteId       LONG,
+    MarketId      LONG,
+    TradeId       LONG,
+    Price         LONG,
+    Quantity      LONG,
+    PriceScale    LONG,
+    QuantityScale LONG,
+    LotSize       LONG,
+    TimeStamp     TIMESTAMP,
+    ts            TIMESTAMP
+) TIMESTAMP(ts) PARTITION BY DAY;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS crypto_order_books
+(
+    Base          SYMBOL,
+    Quote         SYMBOL,
+    MarketName    SYMBOL,
+    BaseQuote     SYMBOL,
+    Side          SYMBOL,
+    BaseId        LONG,
+    QuoteId       LONG,
+    MarketId      LONG,
+    Level         LONG,
+    Price         LONG,
+    Quantity      LONG,
+    PriceScale    LONG,
+    QuantityScale LONG,
+    LotSize       LONG,
+    TimeStamp     TIMESTAMP,
+    ts            TIMESTAMP
+) TIMESTAMP(ts) PARTITION BY DAY;
@@ -0,0 +1,286 @@
+package filerepositories
+
+import (
+	entities2 "DataPoller/internal/common/domain/entities"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"slices"
+)
+
+// Formats WriteCatalog can write. ReadCatalog reads both, JSON being a subset of YAML.
+const (
+	YamlFormat = "yaml"
+	JsonFormat = "json"
+)
+
+// Catalog is the file form of the tds catalog. Symbol pairs refer to markets and
+// symbols, data sources to symbol pairs, by id.
+type Catalog struct {
+	Markets     []CatalogMarket     `yaml:"markets" json:"markets"`
+	Symbols     []CatalogSymbol     `yaml:"symbols" json:"symbols"`
+	SymbolPairs []CatalogSymbolPair `yaml:"symbol_pairs" json:"symbol_pairs"`
+	DataSources []CatalogDataSource `yaml:"data_sources" json:"data_sources"`
+}
+
+type CatalogMarket struct {
+	Id   int    `yaml:"id" json:"id"`
+	Name string `yaml:"name" json:"name"`
+}
+
+type CatalogSymbol struct {
+	Id   int    `yaml:"id" json:"id"`
+	Name string `yaml:"name" json:"name"`
+}
+
+type CatalogSymbolPair struct {
+	Id     int `yaml:"id" json:"id"`
+	Market int `yaml:"market" json:"market"`
+	Base   int `yaml:"base" json:"base"`
+	Quote  int `yaml:"quote" json:"quote"`
+	// PricePrecision and QuantityPrecision fall back to the defaults when missing.
+	PricePrecision    *int32 `yaml:"price_precision,omitempty" json:"price_precision,omitempty"`
+	QuantityPrecision *int32 `yaml:"quantity_precision,omitempty" json:"quantity_precision,omitempty"`
+	LotSize           string `yaml:"lot_size,omitempty" json:"lot_size,omitempty"`
+}
+
+type CatalogDataSource struct {
+	Id               int      `yaml:"id" json:"id"`
+	Name             string   `yaml:"name" json:"name"`
+	ConnectionString string   `yaml:"connection_string" json:"connection_string"`
+	Login            string   `yaml:"login,omitempty" json:"login,omitempty"`
+	Password         string   `yaml:"password,omitempty" json:"password,omitempty"`
+	RateLimit        int      `yaml:"rate_limit" json:"rate_limit"`
+	Channels         []string `yaml:"channels,omitempty" json:"channels,omitempty"`
+	SymbolPairs      []int    `yaml:"symbol_pairs" json:"symbol_pairs"`
+}
+
+// ReadCatalog reads a YAML or JSON catalog file. Unknown fields are rejected, so typos
+// do not silently fall back to defaults.
+func ReadCatalog(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open catalog: %w", err)
+	}
+
+	defer file.Close()
+
+	var catalog Catalog
+	decoder := yaml.NewDecoder(file)
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&catalog); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse catalog %s: %w", path, err)
+	}
+
+	return &catalog, nil
+}
+
+// WriteCatalog writes the catalog in the format, YamlFormat or JsonFormat.
+func WriteCatalog(writer io.Writer, catalog *Catalog, format string) error {
+	switch format {
+	case YamlFormat:
+		encoder := yaml.NewEncoder(writer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(catalog); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case JsonFormat:
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(catalog)
+	default:
+		return fmt.Errorf("unknown catalog format %q, expected %s or %s", format, YamlFormat, JsonFormat)
+	}
+}
+
+// NewCatalog builds the catalog of the data sources, with the markets, symbols and
+// symbol pairs they use.
+func NewCatalog(dataSources []*entities2.DataSource) *Catalog {
+	catalog := &Catalog{}
+	markets := make(map[int]bool)
+	symbols := make(map[int]bool)
+	symbolPairs := make(map[int]bool)
+
+	addSymbol := func(symbol entities2.Symbol) {
+		if !symbols[symbol.Id] {
+			symbols[symbol.Id] = true
+			catalog.Symbols = append(catalog.Symbols, CatalogSymbol{Id: symbol.Id, Name: symbol.Name})
+		}
+	}
+
+	for _, dataSource := range dataSources {
+		catalogDataSource := CatalogDataSource{
+			Id:               dataSource.Id,
+			Name:             dataSource.Name,
+			ConnectionString: dataSource.ConnectionString,
+			Login:            dataSource.Login,
+			Password:         dataSource.Password,
+			RateLimit:        dataSource.RateLimit,
+			Channels:         dataSource.Channels,
+		}
+
+		for _, pair := range dataSource.SymbolPairs {
+			catalogDataSource.SymbolPairs = append(catalogDataSource.SymbolPairs, pair.Id)
+			if symbolPairs[pair.Id] {
+				continue
+			}
+			symbolPairs[pair.Id] = true
+
+			if !markets[pair.Market.Id] {
+				markets[pair.Market.Id] = true
+				catalog.Markets = append(catalog.Markets, CatalogMarket{Id: pair.Market.Id, Name: pair.Market.Name})
+			}
+			addSymbol(pair.BaseSymbol)
+			addSymbol(pair.QuoteSymbol)
+
+			pricePrecision, quantityPrecision := pair.PricePrecision, pair.QuantityPrecision
+			catalogPair := CatalogSymbolPair{
+				Id:                pair.Id,
+				Market:            pair.Market.Id,
+				Base:              pair.BaseSymbol.Id,
+				Quote:             pair.QuoteSymbol.Id,
+				PricePrecision:    &pricePrecision,
+				QuantityPrecision: &quantityPrecision,
+			}
+			if !pair.LotSize.IsZero() {
+				catalogPair.LotSize = pair.LotSize.String()
+			}
+			catalog.SymbolPairs = append(catalog.SymbolPairs, catalogPair)
+		}
+
+		catalog.DataSources = append(catalog.DataSources, catalogDataSource)
+	}
+
+	slices.SortFunc(catalog.Markets, func(a, b CatalogMarket) int { return a.Id - b.Id })
+	slices.SortFunc(catalog.Symbols, func(a, b CatalogSymbol) int { return a.Id - b.Id })
+	slices.SortFunc(catalog.SymbolPairs, func(a, b CatalogSymbolPair) int { return a.Id - b.Id })
+	return catalog
+}
+
+// Resolve turns the catalog into data sources like the joins of the Postgres
+// repository: data sources come ordered by id with their pairs ordered by id, and data
+// sources without pairs are left out.
+func (catalog *Catalog) Resolve() ([]*entities2.DataSource, error) {
+	var errs []error
+	duplicate := func(kind string, id int) {
+		errs = append(errs, fmt.Errorf("duplicate %s id %d", kind, id))
+	}
+
+	markets := make(map[int]entities2.Market)
+	for _, market := range catalog.Markets {
+		if _, found := markets[market.Id]; found {
+			duplicate("market", market.Id)
+		}
+		markets[market.Id] = entities2.Market{Id: market.Id, Name: market.Name}
+	}
+
+	symbols := make(map[int]entities2.Symbol)
+	for _, symbol := range catalog.Symbols {
+		if _, found := symbols[symbol.Id]; found {
+			duplicate("symbol", symbol.Id)
+		}
+		symbols[symbol.Id] = entities2.Symbol{Id: symbol.Id, Name: symbol.Name}
+	}
+
+	symbolPairs := make(map[int]entities2.SymbolPair)
+	// invalidPairs already have an error, data sources referring to them do not add one.
+	invalidPairs := make(map[int]bool)
+	for _, pair := range catalog.SymbolPairs {
+		if _, found := symbolPairs[pair.Id]; found {
+			duplicate("symbol pair", pair.Id)
+		}
+		symbolPair, err := pair.resolve(markets, symbols)
+		if err != nil {
+			errs = append(errs, err)
+			invalidPairs[pair.Id] = true
+			continue
+		}
+		symbolPairs[pair.Id] = symbolPair
+	}
+
+	var dataSources []*entities2.DataSource
+	dataSourceIds := make(map[int]bool)
+	for _, catalogDataSource := range catalog.DataSources {
+		if dataSourceIds[catalogDataSource.Id] {
+			duplicate("data source", catalogDataSource.Id)
+		}
+		dataSourceIds[catalogDataSource.Id] = true
+
+		dataSource := &entities2.DataSource{
+			Id:               catalogDataSource.Id,
+			Name:             catalogDataSource.Name,
+			ConnectionString: catalogDataSource.ConnectionString,
+			Login:            catalogDataSource.Login,
+			Password:         catalogDataSource.Password,
+			RateLimit:        catalogDataSource.RateLimit,
+			Channels:         catalogDataSource.Channels,
+		}
+		for _, pairId := range catalogDataSource.SymbolPairs {
+			symbolPair, found := symbolPairs[pairId]
+			if !found {
+				if !invalidPairs[pairId] {
+					errs = append(errs, fmt.Errorf("data source %d refers to unknown symbol pair %d", dataSource.Id, pairId))
+				}
+				continue
+			}
+			dataSource.SymbolPairs = append(dataSource.SymbolPairs, symbolPair)
+		}
+		if len(dataSource.SymbolPairs) == 0 {
+			continue
+		}
+
+		slices.SortFunc(dataSource.SymbolPairs, func(a, b entities2.SymbolPair) int { return a.Id - b.Id })
+		dataSources = append(dataSources, dataSource)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(dataSources, func(a, b *entities2.DataSource) int { return a.Id - b.Id })
+	return dataSources, nil
+}
+
+func (pair CatalogSymbolPair) resolve(markets map[int]entities2.Market,
+	symbols map[int]entities2.Symbol) (entities2.SymbolPair, error) {
+	market, found := markets[pair.Market]
+	if !found {
+		return entities2.SymbolPair{}, fmt.Errorf("symbol pair %d refers to unknown market %d", pair.Id, pair.Market)
+	}
+	baseSymbol, found := symbols[pair.Base]
+	if !found {
+		return entities2.SymbolPair{}, fmt.Errorf("symbol pair %d refers to unknown symbol %d", pair.Id, pair.Base)
+	}
+	quoteSymbol, found := symbols[pair.Quote]
+	if !found {
+		return entities2.SymbolPair{}, fmt.Errorf("symbol pair %d refers to unknown symbol %d", pair.Id, pair.Quote)
+	}
+
+	symbolPair := entities2.SymbolPair{
+		Id:                pair.Id,
+		Market:            market,
+		BaseSymbol:        baseSymbol,
+		QuoteSymbol:       quoteSymbol,
+		PricePrecision:    entities2.DefaultPricePrecision,
+		QuantityPrecision: entities2.DefaultQuantityPrecision,
+	}
+	if pair.PricePrecision != nil {
+		symbolPair.PricePrecision = *pair.PricePrecision
+	}
+	if pair.QuantityPrecision != nil {
+		symbolPair.QuantityPrecision = *pair.QuantityPrecision
+	}
+	if pair.LotSize != "" {
+		lotSize, err := entities2.ParseDecimal(pair.LotSize)
+		if err != nil {
+			return entities2.SymbolPair{}, fmt.Errorf("invalid lot size of symbol pair %d: %w", pair.Id, err)
+		}
+		symbolPair.LotSize = lotSize
+	}
+
+	return symbolPair, nil
+}
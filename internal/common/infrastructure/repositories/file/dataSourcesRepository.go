@@ -0,0 +1,82 @@
+package filerepositories
+
+import (
+	entities2 "DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"log/slog"
+	"os"
+	"time"
+)
+
+// fileWatchInterval is how often Watch checks the catalog file for changes.
+const fileWatchInterval = time.Second
+
+// FileDataSourcesRepository reads the data sources from a catalog file, see Catalog,
+// for running pollers without the main database. The file is read on every lookup, so
+// edits apply without a restart.
+type FileDataSourcesRepository struct {
+	path string
+}
+
+func NewFileDataSourcesRepository(path string) FileDataSourcesRepository {
+	return FileDataSourcesRepository{path: path}
+}
+
+func (repo FileDataSourcesRepository) FindAll(ctx context.Context) ([]*entities2.DataSource, error) {
+	catalog, err := ReadCatalog(repo.path)
+	if err != nil {
+		return nil, err
+	}
+
+	return catalog.Resolve()
+}
+
+func (repo FileDataSourcesRepository) FindById(ctx context.Context, id int) (*entities2.DataSource, error) {
+	dataSources, err := repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dataSource := range dataSources {
+		if dataSource.Id == id {
+			return dataSource, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// Watch reports every data source as changed whenever the modification time or size
+// of the catalog file changes.
+func (repo FileDataSourcesRepository) Watch(ctx context.Context, changed func(dataSourceId int)) error {
+	last, err := os.Stat(repo.path)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(fileWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(repo.path)
+		if err != nil {
+			// Editors replace the file by renaming, it is back on one of the next ticks.
+			slog.Debug("Error checking the catalog file", "path", repo.path, logging.Error(err))
+			continue
+		}
+		if info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+
+		last = info
+		changed(repositories.AllDataSources)
+	}
+}
@@ -2,22 +2,27 @@ package postgresrepositories
 
 import (
 	entities2 "DataPoller/internal/common/domain/entities"
-	"DataPoller/internal/common/infrastructure"
+	"context"
 	"database/sql"
 	"fmt"
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
+	"time"
 )
 
 type PostgresDataSourcesRepository struct {
+	db           *sql.DB
+	queryTimeout time.Duration
 }
 
-func (repo PostgresDataSourcesRepository) FindAll() ([]*entities2.DataSource, error) {
-	var config infrastructure.Configuration
-	err := config.LoadFromFile()
-	if err != nil {
-		return nil, err
-	}
+// NewPostgresDataSourcesRepository reads data sources through the shared pool, see
+// OpenDatabase. A queryTimeout of zero leaves queries bounded by their context only.
+func NewPostgresDataSourcesRepository(db *sql.DB, queryTimeout time.Duration) PostgresDataSourcesRepository {
+	return PostgresDataSourcesRepository{db: db, queryTimeout: queryTimeout}
+}
 
+// dataSourcesQuery selects one row per symbol pair of a data source, narrowed down by
+// the where clause unless it is empty.
+func dataSourcesQuery(where string) string {
 	query := "SELECT " +
 		"ds.id AS dataSourceId, " +
 		"ds.name AS dataSourceName, " +
@@ -25,13 +30,17 @@ func (repo PostgresDataSourcesRepository) FindAll() ([]*entities2.DataSource, er
 		"ds.login AS dataSourceLogin, " +
 		"ds.password AS dataSourcePassword, " +
 		"ds.rate_limit AS dataSourceRateLimit, " +
+		"ds.channels AS dataSourceChannels, " +
 		"sp.Id AS symbolPairId, " +
 		"m.id as marketId, " +
 		"m.name as marketName, " +
 		"bs.id as baseSymbolId, " +
 		"bs.name as baseSymbolName, " +
 		"qs.id as quoteSymbolId, " +
-		"qs.name as quoteSymbolName " +
+		"qs.name as quoteSymbolName, " +
+		"sp.price_precision AS symbolPairPricePrecision, " +
+		"sp.quantity_precision AS symbolPairQuantityPrecision, " +
+		"sp.lot_size AS symbolPairLotSize " +
 		"FROM tds.data_sources ds " +
 		"INNER JOIN tds.data_source_symbol_pairs dssp ON ds.id = dssp.data_source_id " +
 		"INNER JOIN tds.symbol_pairs sp ON dssp.symbol_pair_id = sp.id " +
@@ -39,23 +48,40 @@ func (repo PostgresDataSourcesRepository) FindAll() ([]*entities2.DataSource, er
 		"INNER JOIN tds.symbols bs ON bs.id = sp.base_symbol_id " +
 		"INNER JOIN tds.symbols qs ON qs.id = sp.quoted_symbol_id "
 
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		config.MainDatabase.Username,
-		config.MainDatabase.Password,
-		config.MainDatabase.Host,
-		config.MainDatabase.Port,
-		config.MainDatabase.Database)
+	if where != "" {
+		query += "WHERE " + where + " "
+	}
+
+	return query + "ORDER BY ds.id, sp.id"
+}
 
-	db, err := sql.Open("postgres", connStr)
+func (repo PostgresDataSourcesRepository) FindAll(ctx context.Context) ([]*entities2.DataSource, error) {
+	return repo.query(ctx, dataSourcesQuery(""))
+}
+
+func (repo PostgresDataSourcesRepository) FindById(ctx context.Context, id int) (*entities2.DataSource, error) {
+	dataSources, err := repo.query(ctx, dataSourcesQuery("ds.id = $1"), id)
 	if err != nil {
 		return nil, err
 	}
-	defer db.Close()
-	if err := db.Ping(); err != nil {
-		return nil, err
+	if len(dataSources) == 0 {
+		return nil, nil
 	}
 
-	rows, err := db.Query(query)
+	return dataSources[0], nil
+}
+
+// query runs a dataSourcesQuery and folds its rows into data sources in the order
+// they were first seen.
+func (repo PostgresDataSourcesRepository) query(ctx context.Context, query string,
+	args ...any) ([]*entities2.DataSource, error) {
+	if repo.queryTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, repo.queryTimeout)
+		defer cancel()
+	}
+
+	rows, err := repo.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -70,6 +96,8 @@ func (repo PostgresDataSourcesRepository) FindAll() ([]*entities2.DataSource, er
 		var market entities2.Market
 		var baseSymbol entities2.Symbol
 		var quoteSymbol entities2.Symbol
+		var pricePrecision, quantityPrecision sql.NullInt32
+		var lotSize sql.NullString
 
 		if err := rows.Scan(&dataSource.Id,
 			&dataSource.Name,
@@ -77,27 +105,31 @@ func (repo PostgresDataSourcesRepository) FindAll() ([]*entities2.DataSource, er
 			&dataSource.Login,
 			&dataSource.Password,
 			&dataSource.RateLimit,
+			pq.Array(&dataSource.Channels),
 			&symbolPair.Id,
 			&market.Id,
 			&market.Name,
 			&baseSymbol.Id,
 			&baseSymbol.Name,
 			&quoteSymbol.Id,
-			&quoteSymbol.Name); err != nil {
+			&quoteSymbol.Name,
+			&pricePrecision,
+			&quantityPrecision,
+			&lotSize); err != nil {
 			return nil, err
 		}
 
-		if existingDataSource, found := dataSourceMap[dataSource.Id]; found {
-			symbolPair.Market = market
-			symbolPair.BaseSymbol = baseSymbol
-			symbolPair.QuoteSymbol = quoteSymbol
+		if err := setSymbolPairPrecision(&symbolPair, pricePrecision, quantityPrecision, lotSize); err != nil {
+			return nil, err
+		}
 
+		symbolPair.Market = market
+		symbolPair.BaseSymbol = baseSymbol
+		symbolPair.QuoteSymbol = quoteSymbol
+
+		if existingDataSource, found := dataSourceMap[dataSource.Id]; found {
 			existingDataSource.SymbolPairs = append(existingDataSource.SymbolPairs, symbolPair)
 		} else {
-			symbolPair.Market = market
-			symbolPair.BaseSymbol = baseSymbol
-			symbolPair.QuoteSymbol = quoteSymbol
-
 			dataSource.SymbolPairs = append(dataSource.SymbolPairs, symbolPair)
 
 			dataSourceMap[dataSource.Id] = &dataSource
@@ -114,102 +146,27 @@ func (repo PostgresDataSourcesRepository) FindAll() ([]*entities2.DataSource, er
 
 }
 
-func (repo PostgresDataSourcesRepository) FindById(id int) (*entities2.DataSource, error) {
-	var config infrastructure.Configuration
-	err := config.LoadFromFile()
-	if err != nil {
-		return nil, err
+// setSymbolPairPrecision copies the precision columns of tds.symbol_pairs to the pair.
+// Pairs without a configured precision use the defaults.
+func setSymbolPairPrecision(symbolPair *entities2.SymbolPair, pricePrecision sql.NullInt32,
+	quantityPrecision sql.NullInt32, lotSize sql.NullString) error {
+	symbolPair.PricePrecision = entities2.DefaultPricePrecision
+	if pricePrecision.Valid {
+		symbolPair.PricePrecision = pricePrecision.Int32
 	}
 
-	query := "SELECT " +
-		"ds.id AS dataSourceId, " +
-		"ds.name AS dataSourceName, " +
-		"ds.connection_string AS dataSourceConnectionString, " +
-		"ds.login AS dataSourceLogin, " +
-		"ds.password AS dataSourcePassword, " +
-		"ds.rate_limit AS dataSourceRateLimit, " +
-		"sp.Id AS symbolPairId, " +
-		"m.id as marketId, " +
-		"m.name as marketName, " +
-		"bs.id as baseSymbolId, " +
-		"bs.name as baseSymbolName, " +
-		"qs.id as quoteSymbolId, " +
-		"qs.name as quoteSymbolName " +
-		"FROM tds.data_sources ds " +
-		"INNER JOIN tds.data_source_symbol_pairs dssp ON ds.id = dssp.data_source_id " +
-		"INNER JOIN tds.symbol_pairs sp ON dssp.symbol_pair_id = sp.id " +
-		"INNER JOIN tds.markets m ON m.id = sp.market_id " +
-		"INNER JOIN tds.symbols bs ON bs.id = sp.base_symbol_id " +
-		"INNER JOIN tds.symbols qs ON qs.id = sp.quoted_symbol_id " +
-		"WHERE ds.id = $1 "
-
-	connStr := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
-		config.MainDatabase.Username,
-		config.MainDatabase.Password,
-		config.MainDatabase.Host,
-		config.MainDatabase.Port,
-		config.MainDatabase.Database)
-
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		return nil, err
+	symbolPair.QuantityPrecision = entities2.DefaultQuantityPrecision
+	if quantityPrecision.Valid {
+		symbolPair.QuantityPrecision = quantityPrecision.Int32
 	}
-	defer db.Close()
 
-	if err := db.Ping(); err != nil {
-		return nil, err
-	}
-
-	rows, err := db.Query(query, id)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var dataSource *entities2.DataSource
-	dataSourceMap := make(map[int]*entities2.DataSource)
-
-	for rows.Next() {
-		var tempDataSource entities2.DataSource
-		var symbolPair entities2.SymbolPair
-		var market entities2.Market
-		var baseSymbol entities2.Symbol
-		var quoteSymbol entities2.Symbol
-
-		if err := rows.Scan(&tempDataSource.Id,
-			&tempDataSource.Name,
-			&tempDataSource.ConnectionString,
-			&tempDataSource.Login,
-			&tempDataSource.Password,
-			&tempDataSource.RateLimit,
-			&symbolPair.Id,
-			&market.Id,
-			&market.Name,
-			&baseSymbol.Id,
-			&baseSymbol.Name,
-			&quoteSymbol.Id,
-			&quoteSymbol.Name); err != nil {
-			return nil, err
-		}
-
-		if existingDataSource, found := dataSourceMap[tempDataSource.Id]; found {
-			symbolPair.Market = market
-			symbolPair.BaseSymbol = baseSymbol
-			symbolPair.QuoteSymbol = quoteSymbol
-			existingDataSource.SymbolPairs = append(existingDataSource.SymbolPairs, symbolPair)
-		} else {
-			symbolPair.Market = market
-			symbolPair.BaseSymbol = baseSymbol
-			symbolPair.QuoteSymbol = quoteSymbol
-			tempDataSource.SymbolPairs = append(tempDataSource.SymbolPairs, symbolPair)
-			dataSourceMap[tempDataSource.Id] = &tempDataSource
-			dataSource = &tempDataSource
+	if lotSize.Valid {
+		parsed, err := entities2.ParseDecimal(lotSize.String)
+		if err != nil {
+			return fmt.Errorf("invalid lot size of symbol pair %d: %w", symbolPair.Id, err)
 		}
+		symbolPair.LotSize = parsed
 	}
 
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-
-	return dataSource, nil
+	return nil
 }
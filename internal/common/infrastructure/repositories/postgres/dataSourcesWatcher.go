@@ -0,0 +1,81 @@
+package postgresrepositories
+
+import (
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"github.com/lib/pq"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// DataSourcesChangedChannel is notified by the triggers of the postgres migration
+// 0004_notify_data_source_changes.
+const DataSourcesChangedChannel = "tds_data_sources_changed"
+
+const (
+	listenerMinReconnectInterval = time.Second
+	listenerMaxReconnectInterval = time.Minute
+	// listenerPingInterval checks an idle connection, a dead one is only noticed when
+	// it is used.
+	listenerPingInterval = 90 * time.Second
+)
+
+// PostgresDataSourcesWatcher reports changes of the tds tables through LISTEN on a
+// dedicated connection, which needs the triggers of the migrations. Notifications
+// sent while the connection was down are lost, so every data source is reported as
+// changed after a reconnect.
+type PostgresDataSourcesWatcher struct {
+	config infrastructure.MainDatabaseConfiguration
+}
+
+func NewPostgresDataSourcesWatcher(config infrastructure.MainDatabaseConfiguration) PostgresDataSourcesWatcher {
+	return PostgresDataSourcesWatcher{config: config}
+}
+
+func (watcher PostgresDataSourcesWatcher) Watch(ctx context.Context, changed func(dataSourceId int)) error {
+	listener := pq.NewListener(connectionString(watcher.config), listenerMinReconnectInterval,
+		listenerMaxReconnectInterval, func(event pq.ListenerEventType, err error) {
+			if err != nil {
+				slog.Warn("Data source changes listener error", logging.Error(err))
+			}
+		})
+	defer listener.Close()
+
+	if err := listener.Listen(DataSourcesChangedChannel); err != nil {
+		return err
+	}
+
+	ping := time.NewTimer(listenerPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			if notification == nil {
+				slog.Info("Data source changes listener reconnected, reloading all data sources")
+				changed(repositories.AllDataSources)
+			} else if dataSourceId, err := strconv.Atoi(notification.Extra); err == nil {
+				changed(dataSourceId)
+			} else {
+				changed(repositories.AllDataSources)
+			}
+		case <-ping.C:
+			if err := listener.Ping(); err != nil {
+				slog.Warn("Error pinging data source changes listener", logging.Error(err))
+			}
+		}
+
+		if !ping.Stop() {
+			select {
+			case <-ping.C:
+			default:
+			}
+		}
+		ping.Reset(listenerPingInterval)
+	}
+}
@@ -0,0 +1,58 @@
+package postgresrepositories
+
+import (
+	"DataPoller/internal/common/infrastructure"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// OpenDatabase opens the connection pool to the main database and checks that it can
+// connect. The pool is meant to be shared and closed by its owner on shutdown.
+func OpenDatabase(ctx context.Context, config infrastructure.MainDatabaseConfiguration) (*sql.DB, error) {
+	db, err := sql.Open("postgres", connectionString(config))
+	if err != nil {
+		return nil, err
+	}
+
+	db.SetMaxOpenConns(config.MaxOpenConnections)
+	db.SetMaxIdleConns(config.MaxIdleConnections)
+	db.SetConnMaxLifetime(config.ConnectionMaxLifetime)
+
+	if config.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.ConnectTimeout)
+		defer cancel()
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", net.JoinHostPort(config.Host, config.Port), err)
+	}
+
+	return db, nil
+}
+
+func connectionString(config infrastructure.MainDatabaseConfiguration) string {
+	query := url.Values{}
+	query.Set("sslmode", config.SslMode)
+	if config.SslRootCert != "" {
+		query.Set("sslrootcert", config.SslRootCert)
+	}
+	if config.ConnectTimeout > 0 {
+		// lib/pq only accepts whole seconds, rounded up so short timeouts do not disable it.
+		query.Set("connect_timeout", strconv.Itoa(int(math.Ceil(config.ConnectTimeout.Seconds()))))
+	}
+
+	connectionUrl := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     net.JoinHostPort(config.Host, config.Port),
+		Path:     config.Database,
+		RawQuery: query.Encode(),
+	}
+	return connectionUrl.String()
+}
@@ -3,69 +3,76 @@ package questrepositories
 import (
 	"DataPoller/internal/common/domain/entities"
 	"DataPoller/internal/common/infrastructure"
-	"strconv"
-
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
 	"context"
-	"fmt"
+	"log/slog"
 
 	qdb "github.com/questdb/go-questdb-client"
 )
 
-type QuestCryptoQuotesWriter struct{}
+// QuestCryptoQuotesWriter writes quotes to the crypto_quotes table in batches.
+type QuestCryptoQuotesWriter struct {
+	*lineWriter[entities.CryptoQuote]
+}
 
-func (repo QuestCryptoQuotesWriter) Write(quotes []entities.CryptoQuote) error {
-	var config infrastructure.Configuration
-	err := config.LoadFromFile()
+func NewQuestCryptoQuotesWriter(config infrastructure.TimeSeriesDatabaseConfiguration,
+	options QuestWriterOptions) (*QuestCryptoQuotesWriter, error) {
+	writer, err := newLineWriter("quotes", config, options, writeQuoteLine)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	return &QuestCryptoQuotesWriter{lineWriter: writer}, nil
+}
 
-	connStr := fmt.Sprintf("%s:%s",
-		config.TimeSeriesDatabase.Host,
-		config.TimeSeriesDatabase.Port)
-
-	ctx := context.TODO()
+func writeQuoteLine(ctx context.Context, sender *qdb.LineSender, quote entities.CryptoQuote) error {
+	baseQuote := quote.SymbolPair.BaseSymbol.Name + quote.SymbolPair.QuoteSymbol.Name
 
-	client, err := qdb.NewLineSender(ctx, qdb.WithAddress(connStr))
+	scaled, err := quote.Scaled()
 	if err != nil {
-		panic("Failed to create QuestDB client")
+		slog.Warn("Skipping quote", "market", quote.Market.Name, logging.SymbolPair(quote.SymbolPair), logging.Error(err))
+		metrics.QuestRowSkipped("quotes", quote.Market.Name, baseQuote)
+		return nil
 	}
-	defer client.Close()
 
-	for _, quote := range quotes {
-		err := client.
-			Table("crypto_quotes").
-			Symbol("Base", quote.SymbolPair.BaseSymbol.Name).
-			Symbol("Quote", quote.SymbolPair.QuoteSymbol.Name).
-			Symbol("MarketName", quote.Market.Name).
-			Symbol("BaseQuote", quote.SymbolPair.BaseSymbol.Name+quote.SymbolPair.QuoteSymbol.Name).
-			Int64Column("BaseId", int64(quote.SymbolPair.BaseSymbol.Id)).
-			Int64Column("QuoteId", int64(quote.SymbolPair.QuoteSymbol.Id)).
-			TimestampColumn("TimeStamp", quote.TimeStamp.UnixMicro()).
-			Int64Column("Rate", int64(quote.Rate)).
-			Int64Column("MarketId", int64(quote.Market.Id)).
-			Int64Column("OpenRate", int64(quote.OpenRate)).
-			Int64Column("HighRate", int64(quote.HighRate)).
-			Int64Column("LowRate", int64(quote.LowRate)).
-			Int64Column("CloseRate", int64(quote.CloseRate)).
-			Int64Column("Volume", int64(quote.Volume)).
-			At(ctx, quote.TimeStamp.UnixMicro())
-		if err != nil {
-			return err
-		}
-	}
+	line := sender.
+		Table("crypto_quotes").
+		Symbol("Base", quote.SymbolPair.BaseSymbol.Name).
+		Symbol("Quote", quote.SymbolPair.QuoteSymbol.Name).
+		Symbol("MarketName", quote.Market.Name).
+		Symbol("BaseQuote", baseQuote).
+		Int64Column("BaseId", int64(quote.SymbolPair.BaseSymbol.Id)).
+		Int64Column("QuoteId", int64(quote.SymbolPair.QuoteSymbol.Id)).
+		TimestampColumn("TimeStamp", quote.TimeStamp.UnixMicro()).
+		Int64Column("Rate", scaled.Rate).
+		Int64Column("MarketId", int64(quote.Market.Id)).
+		Int64Column("OpenRate", scaled.OpenRate).
+		Int64Column("HighRate", scaled.HighRate).
+		Int64Column("LowRate", scaled.LowRate).
+		Int64Column("CloseRate", scaled.CloseRate).
+		Int64Column("Volume", scaled.Volume).
+		Int64Column("PriceScale", int64(quote.SymbolPair.PricePrecision)).
+		Int64Column("QuantityScale", int64(quote.SymbolPair.QuantityPrecision))
 
-	if err := client.Flush(ctx); err != nil {
-		return fmt.Errorf("failed to flush lines to QuestDB: %w", err)
+	// Values an exchange does not provide are left out, so they are stored as null.
+	if !quote.BidRate.IsZero() {
+		line.Int64Column("BidRate", scaled.BidRate)
 	}
-
-	return nil
-}
-
-func ToDatabaseRate(rate string) (uint64, error) {
-	rateFloat, err := strconv.ParseFloat(rate, 64)
-	if err != nil {
-		return 0, fmt.Errorf("error converting rate to float: %v", err)
+	if !quote.AskRate.IsZero() {
+		line.Int64Column("AskRate", scaled.AskRate)
+	}
+	if !quote.BidSize.IsZero() {
+		line.Int64Column("BidSize", scaled.BidSize)
+	}
+	if !quote.AskSize.IsZero() {
+		line.Int64Column("AskSize", scaled.AskSize)
 	}
-	return uint64(rateFloat * 10000), nil
+	if !quote.QuoteVolume.IsZero() {
+		line.Int64Column("QuoteVolume", scaled.QuoteVolume)
+	}
+	if !quote.ReceivedAt.IsZero() {
+		line.TimestampColumn("ReceivedAt", quote.ReceivedAt.UnixMicro())
+	}
+
+	return line.At(ctx, quote.TimeStamp.UnixNano())
 }
@@ -0,0 +1,62 @@
+package questrepositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"errors"
+	"log/slog"
+
+	qdb "github.com/questdb/go-questdb-client"
+)
+
+// QuestCryptoTradesWriter writes trades to the crypto_trades table in batches.
+type QuestCryptoTradesWriter struct {
+	*lineWriter[entities.CryptoTrade]
+}
+
+func NewQuestCryptoTradesWriter(config infrastructure.TimeSeriesDatabaseConfiguration,
+	options QuestWriterOptions) (*QuestCryptoTradesWriter, error) {
+	writer, err := newLineWriter("trades", config, options, writeTradeLine)
+	if err != nil {
+		return nil, err
+	}
+	return &QuestCryptoTradesWriter{lineWriter: writer}, nil
+}
+
+// writeTradeLine stores price and quantity unscaled like crypto_quotes: a stored Price
+// means Price * 10^-PriceScale.
+func writeTradeLine(ctx context.Context, sender *qdb.LineSender, trade entities.CryptoTrade) error {
+	price, priceErr := trade.Price.Rescale(trade.SymbolPair.PricePrecision)
+	quantity, quantityErr := trade.Quantity.Rescale(trade.SymbolPair.QuantityPrecision)
+	if err := errors.Join(priceErr, quantityErr); err != nil {
+		slog.Warn("Skipping trade", "market", trade.Market.Name, logging.SymbolPair(trade.SymbolPair),
+			"trade_id", trade.TradeId, logging.Error(err))
+		return nil
+	}
+
+	line := sender.
+		Table("crypto_trades").
+		Symbol("Base", trade.SymbolPair.BaseSymbol.Name).
+		Symbol("Quote", trade.SymbolPair.QuoteSymbol.Name).
+		Symbol("MarketName", trade.Market.Name).
+		Symbol("BaseQuote", trade.SymbolPair.BaseSymbol.Name+trade.SymbolPair.QuoteSymbol.Name).
+		Symbol("Side", string(trade.Side)).
+		Int64Column("BaseId", int64(trade.SymbolPair.BaseSymbol.Id)).
+		Int64Column("QuoteId", int64(trade.SymbolPair.QuoteSymbol.Id)).
+		Int64Column("MarketId", int64(trade.Market.Id)).
+		Int64Column("TradeId", trade.TradeId).
+		Int64Column("Price", price.Unscaled).
+		Int64Column("Quantity", quantity.Unscaled).
+		Int64Column("PriceScale", int64(trade.SymbolPair.PricePrecision)).
+		Int64Column("QuantityScale", int64(trade.SymbolPair.QuantityPrecision))
+	// Pairs without a known lot size leave it null.
+	if lotSize, found := trade.SymbolPair.ScaledLotSize(); found {
+		line.Int64Column("LotSize", lotSize)
+	}
+
+	return line.
+		TimestampColumn("TimeStamp", trade.TimeStamp.UnixMicro()).
+		At(ctx, trade.TimeStamp.UnixNano())
+}
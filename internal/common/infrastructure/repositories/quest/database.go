@@ -0,0 +1,34 @@
+package questrepositories
+
+import (
+	"DataPoller/internal/common/infrastructure"
+	"context"
+	"database/sql"
+	"fmt"
+	_ "github.com/lib/pq"
+	"net"
+	"net/url"
+)
+
+// OpenDatabase connects to the PostgreSQL wire protocol endpoint of QuestDB, which
+// runs the DDL the line protocol cannot express, see migrations.QuestTarget.
+func OpenDatabase(ctx context.Context, config infrastructure.TimeSeriesDatabaseConfiguration) (*sql.DB, error) {
+	connectionUrl := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(config.Username, config.Password),
+		Host:     net.JoinHostPort(config.Host, config.PgPort),
+		Path:     config.Database,
+		RawQuery: "sslmode=disable",
+	}
+	db, err := sql.Open("postgres", connectionUrl.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s: %w", net.JoinHostPort(config.Host, config.PgPort), err)
+	}
+
+	return db, nil
+}
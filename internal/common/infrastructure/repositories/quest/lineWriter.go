@@ -0,0 +1,213 @@
+package questrepositories
+
+import (
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/health"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	qdb "github.com/questdb/go-questdb-client"
+)
+
+var ErrWriterClosed = errors.New("QuestDB writer is closed")
+
+type QuestWriterOptions struct {
+	// BatchSize is the number of rows that triggers a flush.
+	BatchSize int
+	// FlushInterval is the longest a buffered row waits before it is flushed.
+	FlushInterval time.Duration
+	// BufferSize is the number of rows Write can queue before it blocks.
+	BufferSize int
+	// FlushTimeout bounds connecting to QuestDB and sending a batch, once for the
+	// first attempt and once for the retry.
+	FlushTimeout time.Duration
+}
+
+var DefaultQuestWriterOptions = QuestWriterOptions{
+	BatchSize:     1000,
+	FlushInterval: time.Second,
+	BufferSize:    10000,
+	FlushTimeout:  10 * time.Second,
+}
+
+// lineWriter buffers rows and writes them in batches over a single long-lived ILP
+// connection. Write blocks once the buffer is full, so pollers slow down instead of
+// piling up rows when QuestDB cannot keep up.
+type lineWriter[T any] struct {
+	name    string
+	address string
+	options QuestWriterOptions
+	sender  *qdb.LineSender
+	// writeLine adds one row to the sender. Rows it cannot write are logged and
+	// skipped by returning nil, an error means the connection is broken.
+	writeLine func(ctx context.Context, sender *qdb.LineSender, row T) error
+
+	rows    chan T
+	done    chan struct{}
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+func newLineWriter[T any](name string, config infrastructure.TimeSeriesDatabaseConfiguration, options QuestWriterOptions,
+	writeLine func(ctx context.Context, sender *qdb.LineSender, row T) error) (*lineWriter[T], error) {
+	writer := &lineWriter[T]{
+		name:      name,
+		address:   config.Address(),
+		options:   options,
+		writeLine: writeLine,
+		rows:      make(chan T, options.BufferSize),
+		done:      make(chan struct{}),
+	}
+
+	if err := writer.connect(context.Background()); err != nil {
+		return nil, err
+	}
+	health.DefaultMonitor.WriterStarted(name)
+
+	go writer.run()
+
+	return writer, nil
+}
+
+func (writer *lineWriter[T]) Write(rows []T) error {
+	writer.closeMu.RLock()
+	defer writer.closeMu.RUnlock()
+
+	if writer.closed {
+		return ErrWriterClosed
+	}
+
+	for _, row := range rows {
+		writer.rows <- row
+	}
+
+	return nil
+}
+
+// Close stops accepting rows, flushes everything still buffered and closes the
+// connection to QuestDB.
+func (writer *lineWriter[T]) Close() error {
+	writer.closeMu.Lock()
+	if writer.closed {
+		writer.closeMu.Unlock()
+		return nil
+	}
+	writer.closed = true
+	close(writer.rows)
+	writer.closeMu.Unlock()
+
+	<-writer.done
+
+	if writer.sender == nil {
+		return nil
+	}
+	return writer.sender.Close()
+}
+
+func (writer *lineWriter[T]) connect(ctx context.Context) error {
+	sender, err := qdb.NewLineSender(ctx, qdb.WithAddress(writer.address))
+	if err != nil {
+		return fmt.Errorf("failed to create QuestDB client: %w", err)
+	}
+	writer.sender = sender
+	return nil
+}
+
+// run owns the sender: it collects rows into batches and flushes them when the batch
+// is full, the flush interval elapsed or the writer is closed.
+func (writer *lineWriter[T]) run() {
+	defer close(writer.done)
+
+	ticker := time.NewTicker(writer.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, writer.options.BatchSize)
+
+	for {
+		select {
+		case row, ok := <-writer.rows:
+			if !ok {
+				writer.flush(batch)
+				return
+			}
+
+			batch = append(batch, row)
+			if len(batch) >= writer.options.BatchSize {
+				writer.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			writer.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush sends the batch, reconnecting once if the connection turned out to be broken.
+func (writer *lineWriter[T]) flush(batch []T) {
+	if len(batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	err := writer.flushOrRetry(batch)
+	metrics.ObserveQuestFlush(writer.name, len(batch), time.Since(start), err != nil)
+	health.DefaultMonitor.WriterFlushed(writer.name, err)
+}
+
+// flushOrRetry returns the error the batch was dropped with.
+func (writer *lineWriter[T]) flushOrRetry(batch []T) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writer.options.FlushTimeout)
+	err := writer.send(ctx, batch)
+	cancel()
+	if err == nil {
+		return nil
+	}
+
+	slog.Warn("Error flushing to QuestDB, reconnecting", "writer", writer.name, logging.Error(err))
+
+	if writer.sender != nil {
+		writer.sender.Close()
+		writer.sender = nil
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), writer.options.FlushTimeout)
+	defer cancel()
+	if err := writer.connect(ctx); err != nil {
+		slog.Error("Dropping batch", "writer", writer.name, "rows", len(batch), logging.Error(err))
+		return err
+	}
+
+	if err := writer.send(ctx, batch); err != nil {
+		slog.Error("Dropping batch", "writer", writer.name, "rows", len(batch), logging.Error(err))
+		return err
+	}
+	return nil
+}
+
+func (writer *lineWriter[T]) send(ctx context.Context, batch []T) error {
+	if writer.sender == nil {
+		if err := writer.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, row := range batch {
+		if err := writer.writeLine(ctx, writer.sender, row); err != nil {
+			return err
+		}
+	}
+
+	if err := writer.sender.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush lines to QuestDB: %w", err)
+	}
+
+	return nil
+}
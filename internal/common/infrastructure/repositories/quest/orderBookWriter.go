@@ -0,0 +1,78 @@
+package questrepositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"errors"
+	"log/slog"
+
+	qdb "github.com/questdb/go-questdb-client"
+)
+
+// QuestOrderBookWriter writes order book snapshots to the crypto_order_books table, one
+// row per level. Level 0 is the best bid or ask and all rows of a snapshot share its
+// timestamp.
+type QuestOrderBookWriter struct {
+	*lineWriter[entities.OrderBookSnapshot]
+}
+
+func NewQuestOrderBookWriter(config infrastructure.TimeSeriesDatabaseConfiguration,
+	options QuestWriterOptions) (*QuestOrderBookWriter, error) {
+	writer, err := newLineWriter("order book snapshots", config, options, writeOrderBookLines)
+	if err != nil {
+		return nil, err
+	}
+	return &QuestOrderBookWriter{lineWriter: writer}, nil
+}
+
+func writeOrderBookLines(ctx context.Context, sender *qdb.LineSender, snapshot entities.OrderBookSnapshot) error {
+	sides := []struct {
+		name   string
+		levels []entities.OrderBookLevel
+	}{
+		{"bid", snapshot.Bids},
+		{"ask", snapshot.Asks},
+	}
+
+	for _, side := range sides {
+		for level, orderBookLevel := range side.levels {
+			price, priceErr := orderBookLevel.Price.Rescale(snapshot.SymbolPair.PricePrecision)
+			quantity, quantityErr := orderBookLevel.Quantity.Rescale(snapshot.SymbolPair.QuantityPrecision)
+			if err := errors.Join(priceErr, quantityErr); err != nil {
+				slog.Warn("Skipping order book level", "market", snapshot.Market.Name,
+					logging.SymbolPair(snapshot.SymbolPair), "side", side.name, "level", level, logging.Error(err))
+				continue
+			}
+
+			line := sender.
+				Table("crypto_order_books").
+				Symbol("Base", snapshot.SymbolPair.BaseSymbol.Name).
+				Symbol("Quote", snapshot.SymbolPair.QuoteSymbol.Name).
+				Symbol("MarketName", snapshot.Market.Name).
+				Symbol("BaseQuote", snapshot.SymbolPair.BaseSymbol.Name+snapshot.SymbolPair.QuoteSymbol.Name).
+				Symbol("Side", side.name).
+				Int64Column("BaseId", int64(snapshot.SymbolPair.BaseSymbol.Id)).
+				Int64Column("QuoteId", int64(snapshot.SymbolPair.QuoteSymbol.Id)).
+				Int64Column("MarketId", int64(snapshot.Market.Id)).
+				Int64Column("Level", int64(level)).
+				Int64Column("Price", price.Unscaled).
+				Int64Column("Quantity", quantity.Unscaled).
+				Int64Column("PriceScale", int64(snapshot.SymbolPair.PricePrecision)).
+				Int64Column("QuantityScale", int64(snapshot.SymbolPair.QuantityPrecision))
+			if lotSize, found := snapshot.SymbolPair.ScaledLotSize(); found {
+				line.Int64Column("LotSize", lotSize)
+			}
+
+			err := line.
+				TimestampColumn("TimeStamp", snapshot.TimeStamp.UnixMicro()).
+				At(ctx, snapshot.TimeStamp.UnixNano())
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
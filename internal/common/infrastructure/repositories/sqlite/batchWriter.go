@@ -0,0 +1,181 @@
+package sqliterepositories
+
+import (
+	"DataPoller/internal/common/infrastructure/health"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrWriterClosed = errors.New("SQLite writer is closed")
+
+type SqliteWriterOptions struct {
+	// BatchSize is the number of rows that triggers a flush.
+	BatchSize int
+	// FlushInterval is the longest a buffered row waits before it is flushed.
+	FlushInterval time.Duration
+	// BufferSize is the number of rows Write can queue before it blocks.
+	BufferSize int
+	// FlushTimeout bounds inserting a batch, including the wait for the connection the
+	// writers share.
+	FlushTimeout time.Duration
+}
+
+var DefaultSqliteWriterOptions = SqliteWriterOptions{
+	BatchSize:     1000,
+	FlushInterval: time.Second,
+	BufferSize:    10000,
+	FlushTimeout:  10 * time.Second,
+}
+
+// batchWriter buffers rows and inserts each batch in a single transaction, which is
+// what makes SQLite keep up: committing row by row syncs the WAL for every row. Write
+// blocks once the buffer is full like the QuestDB writers.
+type batchWriter[T any] struct {
+	name    string
+	db      *sql.DB
+	options SqliteWriterOptions
+	insert  string
+	// values returns the values of the rows inserted for one row written. Rows it
+	// cannot store are logged and skipped by returning none.
+	values func(row T) [][]any
+
+	rows    chan T
+	done    chan struct{}
+	closeMu sync.RWMutex
+	closed  bool
+}
+
+func newBatchWriter[T any](name string, db *sql.DB, options SqliteWriterOptions, insert string,
+	values func(row T) [][]any) *batchWriter[T] {
+	writer := &batchWriter[T]{
+		name:    name,
+		db:      db,
+		options: options,
+		insert:  insert,
+		values:  values,
+		rows:    make(chan T, options.BufferSize),
+		done:    make(chan struct{}),
+	}
+	health.DefaultMonitor.WriterStarted(name)
+
+	go writer.run()
+
+	return writer
+}
+
+// insertStatement inserts one row into the columns of the table.
+func insertStatement(table string, columns ...string) string {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + placeholders + ")"
+}
+
+func (writer *batchWriter[T]) Write(rows []T) error {
+	writer.closeMu.RLock()
+	defer writer.closeMu.RUnlock()
+
+	if writer.closed {
+		return ErrWriterClosed
+	}
+
+	for _, row := range rows {
+		writer.rows <- row
+	}
+
+	return nil
+}
+
+// Close stops accepting rows and flushes everything still buffered. The database is
+// shared with the other writers and closed by whoever opened it.
+func (writer *batchWriter[T]) Close() error {
+	writer.closeMu.Lock()
+	if writer.closed {
+		writer.closeMu.Unlock()
+		return nil
+	}
+	writer.closed = true
+	close(writer.rows)
+	writer.closeMu.Unlock()
+
+	<-writer.done
+
+	return nil
+}
+
+// run collects rows into batches and flushes them when the batch is full, the flush
+// interval elapsed or the writer is closed.
+func (writer *batchWriter[T]) run() {
+	defer close(writer.done)
+
+	ticker := time.NewTicker(writer.options.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, writer.options.BatchSize)
+
+	for {
+		select {
+		case row, ok := <-writer.rows:
+			if !ok {
+				writer.flush(batch)
+				return
+			}
+
+			batch = append(batch, row)
+			if len(batch) >= writer.options.BatchSize {
+				writer.flush(batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			writer.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+func (writer *batchWriter[T]) flush(batch []T) {
+	if len(batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	err := writer.insertBatch(batch)
+	if err != nil {
+		slog.Error("Dropping batch", "writer", writer.name, "rows", len(batch), logging.Error(err))
+	}
+	metrics.ObserveSqliteFlush(writer.name, len(batch), time.Since(start), err != nil)
+	health.DefaultMonitor.WriterFlushed(writer.name, err)
+}
+
+func (writer *batchWriter[T]) insertBatch(batch []T) error {
+	ctx, cancel := context.WithTimeout(context.Background(), writer.options.FlushTimeout)
+	defer cancel()
+
+	tx, err := writer.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statement, err := tx.PrepareContext(ctx, writer.insert)
+	if err != nil {
+		return err
+	}
+	defer statement.Close()
+
+	for _, row := range batch {
+		for _, values := range writer.values(row) {
+			if _, err := statement.ExecContext(ctx, values...); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
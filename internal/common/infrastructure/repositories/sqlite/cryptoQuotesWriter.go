@@ -0,0 +1,74 @@
+package sqliterepositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// SqliteCryptoQuotesWriter writes quotes to the crypto_quotes table in batches.
+type SqliteCryptoQuotesWriter struct {
+	*batchWriter[entities.CryptoQuote]
+}
+
+func NewSqliteCryptoQuotesWriter(db *sql.DB, options SqliteWriterOptions) *SqliteCryptoQuotesWriter {
+	insert := insertStatement("crypto_quotes", "Base", "Quote", "MarketName", "BaseQuote", "BaseId", "QuoteId",
+		"MarketId", "TimeStamp", "Rate", "OpenRate", "HighRate", "LowRate", "CloseRate", "Volume", "PriceScale",
+		"QuantityScale", "BidRate", "AskRate", "BidSize", "AskSize", "QuoteVolume", "ReceivedAt")
+	return &SqliteCryptoQuotesWriter{batchWriter: newBatchWriter("quotes", db, options, insert, quoteValues)}
+}
+
+func quoteValues(quote entities.CryptoQuote) [][]any {
+	baseQuote := quote.SymbolPair.BaseSymbol.Name + quote.SymbolPair.QuoteSymbol.Name
+
+	scaled, err := quote.Scaled()
+	if err != nil {
+		slog.Warn("Skipping quote", "market", quote.Market.Name, logging.SymbolPair(quote.SymbolPair), logging.Error(err))
+		metrics.SqliteRowSkipped("quotes", quote.Market.Name, baseQuote)
+		return nil
+	}
+
+	// Values an exchange does not provide are stored as null like in QuestDB.
+	return [][]any{{
+		quote.SymbolPair.BaseSymbol.Name,
+		quote.SymbolPair.QuoteSymbol.Name,
+		quote.Market.Name,
+		baseQuote,
+		quote.SymbolPair.BaseSymbol.Id,
+		quote.SymbolPair.QuoteSymbol.Id,
+		quote.Market.Id,
+		quote.TimeStamp.UnixMicro(),
+		scaled.Rate,
+		scaled.OpenRate,
+		scaled.HighRate,
+		scaled.LowRate,
+		scaled.CloseRate,
+		scaled.Volume,
+		quote.SymbolPair.PricePrecision,
+		quote.SymbolPair.QuantityPrecision,
+		nullable(scaled.BidRate, !quote.BidRate.IsZero()),
+		nullable(scaled.AskRate, !quote.AskRate.IsZero()),
+		nullable(scaled.BidSize, !quote.BidSize.IsZero()),
+		nullable(scaled.AskSize, !quote.AskSize.IsZero()),
+		nullable(scaled.QuoteVolume, !quote.QuoteVolume.IsZero()),
+		nullableTime(quote.ReceivedAt),
+	}}
+}
+
+func nullable(value int64, valid bool) any {
+	if !valid {
+		return nil
+	}
+	return value
+}
+
+// nullableTime stores a time as microseconds since the Unix epoch, the zero time as null.
+func nullableTime(value time.Time) any {
+	if value.IsZero() {
+		return nil
+	}
+	return value.UnixMicro()
+}
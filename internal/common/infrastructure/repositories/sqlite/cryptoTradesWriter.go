@@ -0,0 +1,48 @@
+package sqliterepositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/logging"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// SqliteCryptoTradesWriter writes trades to the crypto_trades table in batches.
+type SqliteCryptoTradesWriter struct {
+	*batchWriter[entities.CryptoTrade]
+}
+
+func NewSqliteCryptoTradesWriter(db *sql.DB, options SqliteWriterOptions) *SqliteCryptoTradesWriter {
+	insert := insertStatement("crypto_trades", "Base", "Quote", "MarketName", "BaseQuote", "Side", "BaseId",
+		"QuoteId", "MarketId", "TradeId", "Price", "Quantity", "PriceScale", "QuantityScale", "LotSize", "TimeStamp")
+	return &SqliteCryptoTradesWriter{batchWriter: newBatchWriter("trades", db, options, insert, tradeValues)}
+}
+
+func tradeValues(trade entities.CryptoTrade) [][]any {
+	price, priceErr := trade.Price.Rescale(trade.SymbolPair.PricePrecision)
+	quantity, quantityErr := trade.Quantity.Rescale(trade.SymbolPair.QuantityPrecision)
+	if err := errors.Join(priceErr, quantityErr); err != nil {
+		slog.Warn("Skipping trade", "market", trade.Market.Name, logging.SymbolPair(trade.SymbolPair),
+			"trade_id", trade.TradeId, logging.Error(err))
+		return nil
+	}
+
+	return [][]any{{
+		trade.SymbolPair.BaseSymbol.Name,
+		trade.SymbolPair.QuoteSymbol.Name,
+		trade.Market.Name,
+		trade.SymbolPair.BaseSymbol.Name + trade.SymbolPair.QuoteSymbol.Name,
+		string(trade.Side),
+		trade.SymbolPair.BaseSymbol.Id,
+		trade.SymbolPair.QuoteSymbol.Id,
+		trade.Market.Id,
+		trade.TradeId,
+		price.Unscaled,
+		quantity.Unscaled,
+		trade.SymbolPair.PricePrecision,
+		trade.SymbolPair.QuantityPrecision,
+		nullable(trade.SymbolPair.ScaledLotSize()),
+		trade.TimeStamp.UnixMicro(),
+	}}
+}
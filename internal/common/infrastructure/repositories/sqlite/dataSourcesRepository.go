@@ -0,0 +1,175 @@
+package sqliterepositories
+
+import (
+	entities2 "DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/domain/repositories"
+	"DataPoller/internal/common/infrastructure/logging"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// catalogWatchInterval is how often Watch checks the catalog version.
+const catalogWatchInterval = time.Second
+
+// SqliteDataSourcesRepository reads the data sources from the catalog tables of the
+// SQLite database, see OpenDatabase.
+type SqliteDataSourcesRepository struct {
+	db *sql.DB
+}
+
+func NewSqliteDataSourcesRepository(db *sql.DB) SqliteDataSourcesRepository {
+	return SqliteDataSourcesRepository{db: db}
+}
+
+// dataSourcesQuery selects one row per symbol pair of a data source like the query of
+// the Postgres repository, narrowed down by the where clause unless it is empty.
+func dataSourcesQuery(where string) string {
+	query := "SELECT " +
+		"ds.id, ds.name, ds.connection_string, ds.login, ds.password, ds.rate_limit, ds.channels, " +
+		"sp.id, m.id, m.name, bs.id, bs.name, qs.id, qs.name, " +
+		"sp.price_precision, sp.quantity_precision, sp.lot_size " +
+		"FROM data_sources ds " +
+		"INNER JOIN data_source_symbol_pairs dssp ON ds.id = dssp.data_source_id " +
+		"INNER JOIN symbol_pairs sp ON dssp.symbol_pair_id = sp.id " +
+		"INNER JOIN markets m ON m.id = sp.market_id " +
+		"INNER JOIN symbols bs ON bs.id = sp.base_symbol_id " +
+		"INNER JOIN symbols qs ON qs.id = sp.quoted_symbol_id "
+
+	if where != "" {
+		query += "WHERE " + where + " "
+	}
+
+	return query + "ORDER BY ds.id, sp.id"
+}
+
+func (repo SqliteDataSourcesRepository) FindAll(ctx context.Context) ([]*entities2.DataSource, error) {
+	return repo.query(ctx, dataSourcesQuery(""))
+}
+
+func (repo SqliteDataSourcesRepository) FindById(ctx context.Context, id int) (*entities2.DataSource, error) {
+	dataSources, err := repo.query(ctx, dataSourcesQuery("ds.id = ?"), id)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataSources) == 0 {
+		return nil, nil
+	}
+
+	return dataSources[0], nil
+}
+
+func (repo SqliteDataSourcesRepository) query(ctx context.Context, query string,
+	args ...any) ([]*entities2.DataSource, error) {
+	rows, err := repo.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var dataSources []*entities2.DataSource
+	dataSourceMap := make(map[int]*entities2.DataSource)
+
+	for rows.Next() {
+		var dataSource entities2.DataSource
+		var symbolPair entities2.SymbolPair
+		var channels sql.NullString
+		var pricePrecision, quantityPrecision sql.NullInt32
+		var lotSize sql.NullString
+
+		if err := rows.Scan(&dataSource.Id,
+			&dataSource.Name,
+			&dataSource.ConnectionString,
+			&dataSource.Login,
+			&dataSource.Password,
+			&dataSource.RateLimit,
+			&channels,
+			&symbolPair.Id,
+			&symbolPair.Market.Id,
+			&symbolPair.Market.Name,
+			&symbolPair.BaseSymbol.Id,
+			&symbolPair.BaseSymbol.Name,
+			&symbolPair.QuoteSymbol.Id,
+			&symbolPair.QuoteSymbol.Name,
+			&pricePrecision,
+			&quantityPrecision,
+			&lotSize); err != nil {
+			return nil, err
+		}
+
+		symbolPair.PricePrecision = entities2.DefaultPricePrecision
+		if pricePrecision.Valid {
+			symbolPair.PricePrecision = pricePrecision.Int32
+		}
+		symbolPair.QuantityPrecision = entities2.DefaultQuantityPrecision
+		if quantityPrecision.Valid {
+			symbolPair.QuantityPrecision = quantityPrecision.Int32
+		}
+		if lotSize.Valid {
+			if symbolPair.LotSize, err = entities2.ParseDecimal(lotSize.String); err != nil {
+				return nil, fmt.Errorf("invalid lot size of symbol pair %d: %w", symbolPair.Id, err)
+			}
+		}
+
+		if existingDataSource, found := dataSourceMap[dataSource.Id]; found {
+			existingDataSource.SymbolPairs = append(existingDataSource.SymbolPairs, symbolPair)
+			continue
+		}
+
+		if channels.Valid {
+			if err := json.Unmarshal([]byte(channels.String), &dataSource.Channels); err != nil {
+				return nil, fmt.Errorf("invalid channels of data source %d: %w", dataSource.Id, err)
+			}
+		}
+		dataSource.SymbolPairs = append(dataSource.SymbolPairs, symbolPair)
+		dataSourceMap[dataSource.Id] = &dataSource
+		dataSources = append(dataSources, &dataSource)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return dataSources, nil
+}
+
+// Watch reports every data source as changed whenever the triggers of the catalog
+// tables counted up catalog_version.
+func (repo SqliteDataSourcesRepository) Watch(ctx context.Context, changed func(dataSourceId int)) error {
+	last, err := repo.catalogVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(catalogWatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		version, err := repo.catalogVersion(ctx)
+		if err != nil {
+			slog.Warn("Error checking the catalog version", logging.Error(err))
+			continue
+		}
+		if version == last {
+			continue
+		}
+
+		last = version
+		changed(repositories.AllDataSources)
+	}
+}
+
+func (repo SqliteDataSourcesRepository) catalogVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := repo.db.QueryRowContext(ctx, "SELECT version FROM catalog_version WHERE id = 1").Scan(&version)
+	return version, err
+}
@@ -0,0 +1,51 @@
+package sqliterepositories
+
+import (
+	"DataPoller/internal/common/infrastructure"
+	"context"
+	"database/sql"
+	_ "embed"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	_ "modernc.org/sqlite"
+)
+
+//go:embed schema.sql
+var schema string
+
+// OpenDatabase opens the SQLite database of the self-contained mode and creates the
+// tables that are missing. Connections use WAL, so the writers do not block readers of
+// the catalog, and wait up to the busy timeout for each other.
+func OpenDatabase(ctx context.Context, config infrastructure.SqliteConfiguration) (*sql.DB, error) {
+	pragmas := url.Values{}
+	pragmas.Add("_pragma", "journal_mode(WAL)")
+	pragmas.Add("_pragma", "synchronous(NORMAL)")
+	pragmas.Add("_pragma", "foreign_keys(ON)")
+	pragmas.Add("_pragma", "busy_timeout("+strconv.FormatInt(config.BusyTimeout.Milliseconds(), 10)+")")
+
+	db, err := sql.Open("sqlite", "file:"+config.Path+"?"+pragmas.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create the tables of %s: %w", config.Path, err)
+	}
+
+	return db, nil
+}
+
+// OpenWriterDatabase opens the database the quotes, trades and order book writers
+// share. It holds a single connection: SQLite has a single writer, more connections
+// would only wait for each other's locks.
+func OpenWriterDatabase(ctx context.Context, config infrastructure.SqliteConfiguration) (*sql.DB, error) {
+	db, err := OpenDatabase(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+	return db, nil
+}
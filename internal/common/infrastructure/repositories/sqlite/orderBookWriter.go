@@ -0,0 +1,63 @@
+package sqliterepositories
+
+import (
+	"DataPoller/internal/common/domain/entities"
+	"DataPoller/internal/common/infrastructure/logging"
+	"database/sql"
+	"errors"
+	"log/slog"
+)
+
+// SqliteOrderBookWriter writes order book snapshots to the crypto_order_books table,
+// one row per level like the QuestDB writer.
+type SqliteOrderBookWriter struct {
+	*batchWriter[entities.OrderBookSnapshot]
+}
+
+func NewSqliteOrderBookWriter(db *sql.DB, options SqliteWriterOptions) *SqliteOrderBookWriter {
+	insert := insertStatement("crypto_order_books", "Base", "Quote", "MarketName", "BaseQuote", "Side", "BaseId",
+		"QuoteId", "MarketId", "Level", "Price", "Quantity", "PriceScale", "QuantityScale", "LotSize", "TimeStamp")
+	return &SqliteOrderBookWriter{batchWriter: newBatchWriter("order book snapshots", db, options, insert, orderBookValues)}
+}
+
+func orderBookValues(snapshot entities.OrderBookSnapshot) [][]any {
+	sides := []struct {
+		name   string
+		levels []entities.OrderBookLevel
+	}{
+		{"bid", snapshot.Bids},
+		{"ask", snapshot.Asks},
+	}
+
+	var rows [][]any
+	for _, side := range sides {
+		for level, orderBookLevel := range side.levels {
+			price, priceErr := orderBookLevel.Price.Rescale(snapshot.SymbolPair.PricePrecision)
+			quantity, quantityErr := orderBookLevel.Quantity.Rescale(snapshot.SymbolPair.QuantityPrecision)
+			if err := errors.Join(priceErr, quantityErr); err != nil {
+				slog.Warn("Skipping order book level", "market", snapshot.Market.Name,
+					logging.SymbolPair(snapshot.SymbolPair), "side", side.name, "level", level, logging.Error(err))
+				continue
+			}
+
+			rows = append(rows, []any{
+				snapshot.SymbolPair.BaseSymbol.Name,
+				snapshot.SymbolPair.QuoteSymbol.Name,
+				snapshot.Market.Name,
+				snapshot.SymbolPair.BaseSymbol.Name + snapshot.SymbolPair.QuoteSymbol.Name,
+				side.name,
+				snapshot.SymbolPair.BaseSymbol.Id,
+				snapshot.SymbolPair.QuoteSymbol.Id,
+				snapshot.Market.Id,
+				level,
+				price.Unscaled,
+				quantity.Unscaled,
+				snapshot.SymbolPair.PricePrecision,
+				snapshot.SymbolPair.QuantityPrecision,
+				nullable(snapshot.SymbolPair.ScaledLotSize()),
+				snapshot.TimeStamp.UnixMicro(),
+			})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,156 @@
+-- The catalog mirrors the tables of the tds schema in Postgres, SQLite having no
+-- schemas. Channels hold a JSON array of channel names.
+
+CREATE TABLE IF NOT EXISTS markets
+(
+    id   INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS symbols
+(
+    id   INTEGER PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE TABLE IF NOT EXISTS symbol_pairs
+(
+    id                 INTEGER PRIMARY KEY,
+    market_id          INTEGER NOT NULL REFERENCES markets (id),
+    base_symbol_id     INTEGER NOT NULL REFERENCES symbols (id),
+    quoted_symbol_id   INTEGER NOT NULL REFERENCES symbols (id),
+    price_precision    INTEGER CHECK (price_precision >= 0),
+    quantity_precision INTEGER CHECK (quantity_precision >= 0),
+    lot_size           TEXT,
+    UNIQUE (market_id, base_symbol_id, quoted_symbol_id)
+);
+
+CREATE TABLE IF NOT EXISTS data_sources
+(
+    id                INTEGER PRIMARY KEY,
+    name              TEXT    NOT NULL UNIQUE,
+    connection_string TEXT    NOT NULL,
+    login             TEXT    NOT NULL DEFAULT '',
+    password          TEXT    NOT NULL DEFAULT '',
+    rate_limit        INTEGER NOT NULL DEFAULT 0 CHECK (rate_limit >= 0),
+    channels          TEXT CHECK (channels IS NULL OR json_valid(channels))
+);
+
+CREATE TABLE IF NOT EXISTS data_source_symbol_pairs
+(
+    data_source_id INTEGER NOT NULL REFERENCES data_sources (id) ON DELETE CASCADE,
+    symbol_pair_id INTEGER NOT NULL REFERENCES symbol_pairs (id) ON DELETE CASCADE,
+    PRIMARY KEY (data_source_id, symbol_pair_id)
+);
+
+-- catalog_version is counted up by the triggers below on every change of the catalog,
+-- see SqliteDataSourcesRepository.Watch.
+CREATE TABLE IF NOT EXISTS catalog_version
+(
+    id      INTEGER PRIMARY KEY CHECK (id = 1),
+    version INTEGER NOT NULL
+);
+
+INSERT OR IGNORE INTO catalog_version (id, version) VALUES (1, 0);
+
+CREATE TRIGGER IF NOT EXISTS markets_updated AFTER UPDATE ON markets
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS markets_deleted AFTER DELETE ON markets
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+
+CREATE TRIGGER IF NOT EXISTS symbols_updated AFTER UPDATE ON symbols
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS symbols_deleted AFTER DELETE ON symbols
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+
+CREATE TRIGGER IF NOT EXISTS symbol_pairs_updated AFTER UPDATE ON symbol_pairs
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS symbol_pairs_deleted AFTER DELETE ON symbol_pairs
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+
+CREATE TRIGGER IF NOT EXISTS data_sources_inserted AFTER INSERT ON data_sources
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS data_sources_updated AFTER UPDATE ON data_sources
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS data_sources_deleted AFTER DELETE ON data_sources
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+
+CREATE TRIGGER IF NOT EXISTS data_source_symbol_pairs_inserted AFTER INSERT ON data_source_symbol_pairs
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS data_source_symbol_pairs_updated AFTER UPDATE ON data_source_symbol_pairs
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+CREATE TRIGGER IF NOT EXISTS data_source_symbol_pairs_deleted AFTER DELETE ON data_source_symbol_pairs
+BEGIN UPDATE catalog_version SET version = version + 1; END;
+
+-- The rows of the writers use the columns of the QuestDB tables. Timestamps are
+-- microseconds since the Unix epoch, values are unscaled like in QuestDB.
+
+CREATE TABLE IF NOT EXISTS crypto_quotes
+(
+    Base          TEXT    NOT NULL,
+    Quote         TEXT    NOT NULL,
+    MarketName    TEXT    NOT NULL,
+    BaseQuote     TEXT    NOT NULL,
+    BaseId        INTEGER NOT NULL,
+    QuoteId       INTEGER NOT NULL,
+    MarketId      INTEGER NOT NULL,
+    TimeStamp     INTEGER NOT NULL,
+    Rate          INTEGER NOT NULL,
+    OpenRate      INTEGER NOT NULL,
+    HighRate      INTEGER NOT NULL,
+    LowRate       INTEGER NOT NULL,
+    CloseRate     INTEGER NOT NULL,
+    Volume        INTEGER NOT NULL,
+    PriceScale    INTEGER NOT NULL,
+    QuantityScale INTEGER NOT NULL,
+    BidRate       INTEGER,
+    AskRate       INTEGER,
+    BidSize       INTEGER,
+    AskSize       INTEGER,
+    QuoteVolume   INTEGER,
+    ReceivedAt    INTEGER
+);
+
+CREATE INDEX IF NOT EXISTS crypto_quotes_pair_time ON crypto_quotes (MarketId, BaseId, QuoteId, TimeStamp);
+
+CREATE TABLE IF NOT EXISTS crypto_trades
+(
+    Base          TEXT    NOT NULL,
+    Quote         TEXT    NOT NULL,
+    MarketName    TEXT    NOT NULL,
+    BaseQuote     TEXT    NOT NULL,
+    Side          TEXT    NOT NULL,
+    BaseId        INTEGER NOT NULL,
+    QuoteId       INTEGER NOT NULL,
+    MarketId      INTEGER NOT NULL,
+    TradeId       INTEGER NOT NULL,
+    Price         INTEGER NOT NULL,
+    Quantity      INTEGER NOT NULL,
+    PriceScale    INTEGER NOT NULL,
+    QuantityScale INTEGER NOT NULL,
+    LotSize       INTEGER,
+    TimeStamp     INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS crypto_trades_pair_time ON crypto_trades (MarketId, BaseId, QuoteId, TimeStamp);
+
+CREATE TABLE IF NOT EXISTS crypto_order_books
+(
+    Base          TEXT    NOT NULL,
+    Quote         TEXT    NOT NULL,
+    MarketName    TEXT    NOT NULL,
+    BaseQuote     TEXT    NOT NULL,
+    Side          TEXT    NOT NULL,
+    BaseId        INTEGER NOT NULL,
+    QuoteId       INTEGER NOT NULL,
+    MarketId      INTEGER NOT NULL,
+    Level         INTEGER NOT NULL,
+    Price         INTEGER NOT NULL,
+    Quantity      INTEGER NOT NULL,
+    PriceScale    INTEGER NOT NULL,
+    QuantityScale INTEGER NOT NULL,
+    LotSize       INTEGER,
+    TimeStamp     INTEGER NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS crypto_order_books_pair_time ON crypto_order_books (MarketId, BaseId, QuoteId, TimeStamp);
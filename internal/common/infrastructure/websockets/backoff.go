@@ -0,0 +1,51 @@
+package websockets
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// BackoffPolicy controls how long a ReconnectingConn waits between connection attempts.
+type BackoffPolicy struct {
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	// Jitter is the fraction of the interval that is randomised, 0.2 spreads retries
+	// over [0.8, 1.2] of the computed interval.
+	Jitter float64
+	// MaxRetries is the number of consecutive failed attempts after which the connection
+	// gives up. Zero retries forever.
+	MaxRetries int
+}
+
+var DefaultBackoffPolicy = BackoffPolicy{
+	InitialInterval: time.Second,
+	MaxInterval:     time.Minute,
+	Multiplier:      2,
+	Jitter:          0.2,
+	MaxRetries:      0,
+}
+
+// Interval returns the delay before the given attempt, counted from 1.
+func (policy BackoffPolicy) Interval(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	interval := float64(policy.InitialInterval) * math.Pow(policy.Multiplier, float64(attempt-1))
+	if interval > float64(policy.MaxInterval) {
+		interval = float64(policy.MaxInterval)
+	}
+
+	if policy.Jitter > 0 {
+		interval += interval * policy.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(interval)
+}
+
+// Exhausted reports whether the policy allows no further attempt.
+func (policy BackoffPolicy) Exhausted(attempt int) bool {
+	return policy.MaxRetries > 0 && attempt > policy.MaxRetries
+}
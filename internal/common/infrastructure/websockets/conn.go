@@ -0,0 +1,49 @@
+package websockets
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// controlWriteTimeout bounds how long writing a close, ping or pong frame may take.
+const controlWriteTimeout = time.Second
+
+// Conn is a websocket connection that can be written to from several goroutines,
+// e.g. the read loop, a ping loop and the shutdown hook.
+type Conn struct {
+	*websocket.Conn
+	writeMu sync.Mutex
+}
+
+func Dial(ctx context.Context, url string) (*Conn, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Conn{Conn: conn}, nil
+}
+
+func (conn *Conn) WriteMessage(messageType int, data []byte) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+
+	return conn.Conn.WriteMessage(messageType, data)
+}
+
+func (conn *Conn) WriteJSON(v interface{}) error {
+	conn.writeMu.Lock()
+	defer conn.writeMu.Unlock()
+
+	return conn.Conn.WriteJSON(v)
+}
+
+// CloseGracefully sends a close frame before closing the underlying connection, which
+// also unblocks a pending ReadMessage.
+func (conn *Conn) CloseGracefully() error {
+	message := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	_ = conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(controlWriteTimeout))
+	return conn.Close()
+}
@@ -0,0 +1,297 @@
+package websockets
+
+import (
+	"DataPoller/internal/common/infrastructure/health"
+	"DataPoller/internal/common/infrastructure/logging"
+	"DataPoller/internal/common/infrastructure/metrics"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrReconnect can be returned from Handler.HandleMessage to drop the current
+// connection and open a new one, e.g. when the exchange announces a restart.
+var ErrReconnect = errors.New("reconnect requested")
+
+// Handler holds the exchange specific parts of a ReconnectingConn.
+type Handler struct {
+	// Subscribe is called on every new connection and must subscribe everything the
+	// connection owns, so a reconnect restores exactly the previous subscriptions.
+	// With Options.MaxLifetime set it is called while the previous connection is still
+	// delivering messages.
+	Subscribe func(ctx context.Context, conn *Conn) error
+	// HandleMessage is called for every message read from the connection, one call at
+	// a time. During a rotation the new connection is only read once the old one is
+	// closed and its last call returned, so its first messages wait in the socket until
+	// then. receivedAt is taken right after the read.
+	HandleMessage func(conn *Conn, message []byte, receivedAt time.Time) error
+	// Unsubscribe is called on shutdown right before the connection is closed.
+	Unsubscribe func(conn *Conn)
+}
+
+type Options struct {
+	Backoff BackoffPolicy
+	// ReadTimeout drops the connection when no message, ping or pong has been read for
+	// this long. Zero disables the watchdog.
+	ReadTimeout time.Duration
+	// PingInterval sends websocket pings so quiet connections still produce pongs for
+	// the read watchdog. Zero disables pinging.
+	PingInterval time.Duration
+	// MaxLifetime rotates the connection before the exchange drops it: a new connection
+	// is opened and subscribed before the old one is closed. Zero disables rotation.
+	MaxLifetime time.Duration
+}
+
+// DefaultOptions drop connections that stayed silent for a minute. Pollers of exchanges
+// with a faster heartbeat use a shorter ReadTimeout.
+var DefaultOptions = Options{
+	Backoff:     DefaultBackoffPolicy,
+	ReadTimeout: time.Minute,
+}
+
+// ReconnectingConn keeps a websocket subscription alive, redialing with exponential
+// backoff whenever dialing, subscribing or reading fails.
+type ReconnectingConn struct {
+	name       string
+	url        string
+	options    Options
+	handler    Handler
+	logger     *slog.Logger
+	handleMu   sync.Mutex
+	reconnects atomic.Int64
+}
+
+// session is a subscribed connection together with its shutdown hook.
+type session struct {
+	conn         *Conn
+	stopShutdown func() bool
+}
+
+func NewReconnectingConn(name string, url string, options Options, handler Handler) *ReconnectingConn {
+	return &ReconnectingConn{
+		name:    name,
+		url:     url,
+		options: options,
+		handler: handler,
+		logger:  slog.With(logging.ConnectionIdKey, name),
+	}
+}
+
+// Reconnects returns how many times the connection has been re-established.
+func (rc *ReconnectingConn) Reconnects() int64 {
+	return rc.reconnects.Load()
+}
+
+// Run connects and serves messages until ctx is cancelled, in which case it returns
+// nil, or until the backoff policy is exhausted.
+func (rc *ReconnectingConn) Run(ctx context.Context) error {
+	attempt := 0
+
+	for {
+		current, err := rc.open(ctx)
+		if err == nil {
+			health.DefaultMonitor.ConnectionUp(rc.name)
+			openedAt := time.Now()
+			err = rc.serve(ctx, current)
+			// A server that accepts and drops connections right away must still exhaust
+			// the backoff, so only a connection that lasted counts as recovered.
+			if time.Since(openedAt) >= rc.stableAfter() {
+				attempt = 0
+			}
+		}
+		if ctx.Err() != nil {
+			health.DefaultMonitor.ConnectionClosed(rc.name)
+			return nil
+		}
+
+		attempt++
+		if rc.options.Backoff.Exhausted(attempt) {
+			err = fmt.Errorf("%s: giving up after %d attempts: %w", rc.name, attempt-1, err)
+			health.DefaultMonitor.ConnectionGaveUp(rc.name, err)
+			return err
+		}
+		health.DefaultMonitor.ConnectionDown(rc.name, err)
+
+		delay := rc.options.Backoff.Interval(attempt)
+		rc.logger.Warn("Connection failed, reconnecting", logging.Error(err),
+			"delay", delay.Round(time.Millisecond), "attempt", attempt)
+
+		select {
+		case <-ctx.Done():
+			health.DefaultMonitor.ConnectionClosed(rc.name)
+			return nil
+		case <-time.After(delay):
+		}
+
+		rc.reconnects.Add(1)
+		metrics.Reconnected(rc.name)
+	}
+}
+
+// stableAfter is how long a connection must last before the backoff starts over: one
+// read timeout, or the default one when the watchdog is disabled.
+func (rc *ReconnectingConn) stableAfter() time.Duration {
+	if rc.options.ReadTimeout > 0 {
+		return rc.options.ReadTimeout
+	}
+	return DefaultOptions.ReadTimeout
+}
+
+// open dials and subscribes a new connection.
+func (rc *ReconnectingConn) open(ctx context.Context) (*session, error) {
+	conn, err := Dial(ctx, rc.url)
+	if err != nil {
+		return nil, fmt.Errorf("dial failed: %w", err)
+	}
+
+	rc.installControlHandlers(conn)
+
+	stopShutdown := context.AfterFunc(ctx, func() {
+		if rc.handler.Unsubscribe != nil {
+			rc.handler.Unsubscribe(conn)
+		}
+		conn.CloseGracefully()
+	})
+
+	if err := rc.handler.Subscribe(ctx, conn); err != nil {
+		stopShutdown()
+		conn.Close()
+		return nil, fmt.Errorf("subscribe failed: %w", err)
+	}
+	metrics.ConnectionOpened(rc.name)
+
+	return &session{conn: conn, stopShutdown: stopShutdown}, nil
+}
+
+// close closes a session that is no longer needed without unsubscribing it.
+func (current *session) close() {
+	current.stopShutdown()
+	current.conn.CloseGracefully()
+}
+
+// serve reads from the session until it fails, rotating it once it reaches
+// Options.MaxLifetime.
+func (rc *ReconnectingConn) serve(ctx context.Context, current *session) error {
+	var rotate <-chan time.Time
+	if rc.options.MaxLifetime > 0 {
+		rotate = time.After(rc.options.MaxLifetime)
+	}
+
+	readErr := rc.startReading(current)
+	rotationAttempt := 0
+
+	for {
+		select {
+		case err := <-readErr:
+			if !current.stopShutdown() {
+				// The shutdown hook already runs and closes the connection itself.
+				return err
+			}
+			current.conn.Close()
+			return err
+
+		case <-rotate:
+			next, err := rc.open(ctx)
+			if err != nil {
+				if ctx.Err() != nil {
+					continue
+				}
+				rotationAttempt++
+				delay := rc.options.Backoff.Interval(rotationAttempt)
+				rc.logger.Warn("Connection rotation failed, retrying", logging.Error(err),
+					"delay", delay.Round(time.Millisecond))
+				rotate = time.After(delay)
+				continue
+			}
+
+			// The new connection is subscribed, so the old one can go without a gap.
+			current.close()
+			<-readErr
+			rc.logger.Info("Rotated connection")
+
+			current = next
+			readErr = rc.startReading(current)
+			rotate = time.After(rc.options.MaxLifetime)
+			rotationAttempt = 0
+		}
+	}
+}
+
+func (rc *ReconnectingConn) startReading(current *session) <-chan error {
+	readErr := make(chan error, 1)
+	go func() {
+		err := rc.read(current.conn)
+		// A connection is only left once reading from it failed or it was closed.
+		metrics.ConnectionClosed(rc.name)
+		readErr <- err
+	}()
+
+	if rc.options.PingInterval > 0 {
+		go rc.ping(current.conn)
+	}
+
+	return readErr
+}
+
+func (rc *ReconnectingConn) read(conn *Conn) error {
+	for {
+		rc.extendReadDeadline(conn)
+
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return fmt.Errorf("read failed: %w", err)
+		}
+		receivedAt := time.Now()
+
+		rc.handleMu.Lock()
+		err = rc.handler.HandleMessage(conn, message, receivedAt)
+		rc.handleMu.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// ping sends pings until a write fails, which happens once the connection is closed.
+func (rc *ReconnectingConn) ping(conn *Conn) {
+	ticker := time.NewTicker(rc.options.PingInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		deadline := time.Now().Add(rc.options.PingInterval)
+		if err := conn.WriteControl(websocket.PingMessage, nil, deadline); err != nil {
+			return
+		}
+	}
+}
+
+// installControlHandlers answers server pings and feeds pings and pongs to the read
+// watchdog, so a connection that only exchanges control frames is not dropped.
+func (rc *ReconnectingConn) installControlHandlers(conn *Conn) {
+	conn.SetPingHandler(func(appData string) error {
+		rc.extendReadDeadline(conn)
+
+		err := conn.WriteControl(websocket.PongMessage, []byte(appData), time.Now().Add(controlWriteTimeout))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+		return err
+	})
+
+	conn.SetPongHandler(func(string) error {
+		rc.extendReadDeadline(conn)
+		return nil
+	})
+}
+
+func (rc *ReconnectingConn) extendReadDeadline(conn *Conn) {
+	if rc.options.ReadTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(rc.options.ReadTimeout))
+	}
+}